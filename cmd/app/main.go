@@ -3,8 +3,11 @@ package main
 import (
 	"context"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/domain"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/config"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/container"
 	"github.com/GoogleCloudPlatform/functions-framework-go/funcframework"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 )
@@ -23,10 +26,30 @@ func main() {
 		port = envPort
 	}
 
+	// Metrics are served on their own port, so they are not exposed next to the public API.
+	// Use METRICS_PORT environment variable, or default to 9090.
+	metricsPort := "9090"
+	if envPort := os.Getenv("METRICS_PORT"); envPort != "" {
+		metricsPort = envPort
+	}
+	metricsHandler, err := container.MetricsHandler(config.LoadConfig())
+	if err != nil {
+		log.Fatalf("MetricsHandler: %v\n", err)
+	}
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", metricsHandler)
+	metricsServer := &http.Server{Addr: ":" + metricsPort, Handler: metricsMux}
+	defer metricsServer.Close()
+
 	funcFrameworkError := make(chan error, 1)
 	go func() {
 		funcFrameworkError <- funcframework.Start(port)
 	}()
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("metrics ListenAndServe: %v\n", err)
+		}
+	}()
 
 	select {
 	case <-ctx.Done():
@@ -36,4 +59,8 @@ func main() {
 			log.Fatalf("funcframework.Start: %v\n", err)
 		}
 	}
+
+	if err := container.Shutdown(); err != nil {
+		log.Printf("Shutdown: %v\n", err)
+	}
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/prompts"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/services"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/eval"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/catalog"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/config"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/libs/tracer"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/genai"
+)
+
+func main() {
+	cfg := config.LoadConfig()
+
+	datasetDir := flag.String("dataset", "", "directory with images and "+eval.LabelsFile)
+	jsonPath := flag.String("json", "", "write the JSON report to this file")
+	markdownPath := flag.String("markdown", "", "write the Markdown report to this file")
+	replayPath := flag.String("replay", "", "answer from this recordings file instead of calling the model")
+	recordPath := flag.String("record", "", "record the model responses to this file")
+	languages := flag.String("languages", "de,en", "comma separated languages to translate the results into")
+	model := flag.String("model", cfg.GeminiModel, "classification model")
+	classificationPrompt := flag.String("classification-prompt", cfg.ClassificationPromptVersion, "classification prompt version")
+	translationModel := flag.String("translation-model", cfg.TranslationModel, "translation model")
+	translationPrompt := flag.String("translation-prompt", cfg.TranslationPromptVersion, "translation prompt version")
+	catalogPath := flag.String("catalog", cfg.CatalogPath, "product catalog to look up barcodes in")
+	flag.Parse()
+
+	if *datasetDir == "" {
+		log.Fatalf("-dataset is required\n")
+	}
+	if *replayPath != "" && *recordPath != "" {
+		log.Fatalf("-replay and -record are mutually exclusive\n")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	dataset, err := eval.LoadDataset(*datasetDir)
+	if err != nil {
+		log.Fatalf("LoadDataset: %v\n", err)
+	}
+
+	var classifier services.Classifier
+	var translator services.Translator
+	if *replayPath != "" {
+		recordings, err := eval.LoadRecordings(*replayPath)
+		if err != nil {
+			log.Fatalf("LoadRecordings: %v\n", err)
+		}
+		classifier = recordings.Classifier(nil)
+		translator = recordings.Translator(nil)
+	} else {
+		geminiClient, err := genai.NewClient(ctx, &genai.ClientConfig{
+			HTTPOptions: genai.HTTPOptions{APIVersion: "v1"},
+			Backend:     genai.BackendVertexAI,
+			Project:     cfg.ProjectID,
+			Location:    "europe-west4",
+		})
+		if err != nil {
+			log.Fatalf("genai.NewClient: %v\n", err)
+		}
+
+		promptRegistry, err := prompts.NewRegistry()
+		if err != nil {
+			log.Fatalf("NewRegistry: %v\n", err)
+		}
+		classification, err := promptRegistry.Get(prompts.Classification, *classificationPrompt)
+		if err != nil {
+			log.Fatalf("Get classification prompt: %v\n", err)
+		}
+		translation, err := promptRegistry.Get(prompts.Translation, *translationPrompt)
+		if err != nil {
+			log.Fatalf("Get translation prompt: %v\n", err)
+		}
+
+		classifier = services.NewGeminiClassifier(geminiClient, *model, classification)
+		translator = services.NewGeminiTranslator(geminiClient, *translationModel, translation)
+	}
+
+	var recordings *eval.Recordings
+	if *recordPath != "" {
+		recordings = eval.NewRecordings()
+		classifier = recordings.Classifier(classifier)
+		translator = recordings.Translator(translator)
+	}
+
+	productCatalog, err := catalog.NewFileCatalog(*catalogPath)
+	if err != nil {
+		log.Fatalf("NewFileCatalog: %v\n", err)
+	}
+
+	// The service prepares the images like uploads, without captcha, history or experiments
+	service := services.NewWasteSortingService(classifier, translator, localization.NewLocalizer(), services.CaptchaVerifiers{},
+		services.NewImagePreprocessor(cfg.ImageMaxEdge, cfg.ImageJPEGQuality), services.ImageLimits{
+			MaxImages:     cfg.MaxImages,
+			MaxTotalBytes: cfg.MaxImagesBytes,
+		}, productCatalog, nil, nil, time.Now, &stderrLogger{}, tracer.New(nil, noop.NewTracerProvider().Tracer("eval")))
+
+	runner := &eval.Runner{Service: service}
+	for _, language := range strings.Split(*languages, ",") {
+		if language = strings.TrimSpace(language); language != "" {
+			runner.Languages = append(runner.Languages, language)
+		}
+	}
+
+	report := runner.Run(ctx, dataset)
+
+	if recordings != nil {
+		if err := recordings.Save(*recordPath); err != nil {
+			log.Fatalf("Save recordings: %v\n", err)
+		}
+	}
+
+	if *jsonPath != "" {
+		writeReport(*jsonPath, report.WriteJSON)
+	}
+	if *markdownPath != "" {
+		writeReport(*markdownPath, report.WriteMarkdown)
+	}
+	if *jsonPath == "" && *markdownPath == "" {
+		if err := report.WriteMarkdown(os.Stdout); err != nil {
+			log.Fatalf("WriteMarkdown: %v\n", err)
+		}
+	}
+
+	log.Printf("Accuracy %.1f%% (%d of %d cases, %d failed)\n", report.Accuracy*100, report.Correct, report.Cases, report.Failed)
+}
+
+// writeReport creates the file at path and writes the report into it
+func writeReport(path string, write func(w io.Writer) error) {
+	file, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("Create %s: %v\n", path, err)
+	}
+	defer file.Close()
+
+	if err := write(file); err != nil {
+		log.Fatalf("Write %s: %v\n", path, err)
+	}
+}
+
+// stderrLogger writes warnings and errors of the service, such as failed barcode lookups, to the standard error
+type stderrLogger struct{}
+
+func (*stderrLogger) Debug(context.Context, interface{}) {}
+
+func (*stderrLogger) Info(context.Context, interface{}) {}
+
+func (*stderrLogger) Warning(_ context.Context, payload interface{}) {
+	log.Printf("WARNING %v\n", payload)
+}
+
+func (*stderrLogger) Error(_ context.Context, payload interface{}) {
+	log.Printf("ERROR %v\n", payload)
+}
+
+func (*stderrLogger) Critical(_ context.Context, payload interface{}) {
+	log.Printf("CRITICAL %v\n", payload)
+}
+
+func (*stderrLogger) Close(context.Context) error {
+	return nil
+}
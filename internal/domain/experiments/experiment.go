@@ -0,0 +1,99 @@
+package experiments
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Variant is an arm of an experiment. Empty fields use the configured defaults.
+type Variant struct {
+	Name                 string `json:"name"`
+	Weight               int    `json:"weight"`
+	Model                string `json:"model,omitempty"`
+	ClassificationPrompt string `json:"classification_prompt,omitempty"`
+	TranslationModel     string `json:"translation_model,omitempty"`
+	TranslationPrompt    string `json:"translation_prompt,omitempty"`
+}
+
+// Experiment compares prompt and model variants on live traffic
+type Experiment struct {
+	Name     string     `json:"name"`
+	Variants []*Variant `json:"variants"`
+}
+
+// Load reads the experiment from the JSON file at path.
+// An empty path returns nil, no experiment is running then.
+func Load(path string) (*Experiment, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading experiment: %v", err)
+	}
+
+	var experiment Experiment
+	if err := json.Unmarshal(data, &experiment); err != nil {
+		return nil, fmt.Errorf("error parsing experiment: %v", err)
+	}
+	if err := experiment.validate(); err != nil {
+		return nil, err
+	}
+
+	return &experiment, nil
+}
+
+// Assign returns the variant of the client. The assignment only depends on the experiment name,
+// the client ID and the weights, so a client keeps its variant across requests and instances.
+func (e *Experiment) Assign(clientID string) *Variant {
+	sum := sha256.Sum256([]byte(e.Name + ":" + clientID))
+	point := binary.BigEndian.Uint64(sum[:8]) % uint64(e.totalWeight())
+
+	for _, variant := range e.Variants {
+		if point < uint64(variant.Weight) {
+			return variant
+		}
+		point -= uint64(variant.Weight)
+	}
+
+	return e.Variants[len(e.Variants)-1]
+}
+
+// totalWeight returns the sum of the variant weights
+func (e *Experiment) totalWeight() int {
+	total := 0
+	for _, variant := range e.Variants {
+		total += variant.Weight
+	}
+	return total
+}
+
+// validate checks that the experiment has uniquely named variants and a positive total weight
+func (e *Experiment) validate() error {
+	if e.Name == "" {
+		return fmt.Errorf("experiment has no name")
+	}
+	if len(e.Variants) == 0 {
+		return fmt.Errorf("experiment %s has no variants", e.Name)
+	}
+
+	names := map[string]bool{}
+	for _, variant := range e.Variants {
+		if variant.Name == "" || names[variant.Name] {
+			return fmt.Errorf("experiment %s has a variant without a unique name", e.Name)
+		}
+		if variant.Weight < 0 {
+			return fmt.Errorf("experiment %s has a negative weight for variant %s", e.Name, variant.Name)
+		}
+		names[variant.Name] = true
+	}
+	if e.totalWeight() == 0 {
+		return fmt.Errorf("experiment %s has no weighted variants", e.Name)
+	}
+
+	return nil
+}
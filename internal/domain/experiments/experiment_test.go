@@ -0,0 +1,94 @@
+package experiments
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testExperiment splits clients 1:3 between control and treatment
+func testExperiment() *Experiment {
+	return &Experiment{
+		Name: "prompt-v2",
+		Variants: []*Variant{
+			{Name: "control", Weight: 1},
+			{Name: "treatment", Weight: 3, ClassificationPrompt: "v2"},
+		},
+	}
+}
+
+func TestAssignIsDeterministic(t *testing.T) {
+	experiment := testExperiment()
+
+	for i := 0; i < 100; i++ {
+		clientID := fmt.Sprintf("device-%d", i)
+		want := experiment.Assign(clientID)
+		// A reloaded experiment assigns the same variant, as other instances do
+		if got := testExperiment().Assign(clientID); got.Name != want.Name {
+			t.Fatalf("Assign(%s) = %s, then %s", clientID, want.Name, got.Name)
+		}
+	}
+}
+
+func TestAssignFollowsWeights(t *testing.T) {
+	experiment := testExperiment()
+
+	const clients = 20000
+	counts := map[string]int{}
+	for i := 0; i < clients; i++ {
+		counts[experiment.Assign(fmt.Sprintf("device-%d", i)).Name]++
+	}
+
+	total := float64(experiment.totalWeight())
+	for _, variant := range experiment.Variants {
+		share := float64(counts[variant.Name]) / clients
+		want := float64(variant.Weight) / total
+		// Five standard deviations of the binomial share, so the test only fails on a biased assignment
+		if tolerance := 5 * math.Sqrt(want*(1-want)/clients); math.Abs(share-want) > tolerance {
+			t.Errorf("variant %s got %.3f of the clients, want %.3f ± %.3f", variant.Name, share, want, tolerance)
+		}
+	}
+}
+
+func TestAssignSkipsUnweightedVariants(t *testing.T) {
+	experiment := &Experiment{
+		Name:     "paused",
+		Variants: []*Variant{{Name: "paused", Weight: 0}, {Name: "control", Weight: 1}},
+	}
+
+	for i := 0; i < 100; i++ {
+		if variant := experiment.Assign(fmt.Sprintf("device-%d", i)); variant.Name != "control" {
+			t.Fatalf("Assign() = %s, want control", variant.Name)
+		}
+	}
+}
+
+func TestLoadValidatesExperiment(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+	}{
+		{"valid", `{"name":"prompt-v2","variants":[{"name":"control","weight":1},{"name":"treatment","weight":1}]}`, false},
+		{"no name", `{"variants":[{"name":"control","weight":1}]}`, true},
+		{"no variants", `{"name":"prompt-v2"}`, true},
+		{"duplicate variant", `{"name":"prompt-v2","variants":[{"name":"control","weight":1},{"name":"control","weight":1}]}`, true},
+		{"negative weight", `{"name":"prompt-v2","variants":[{"name":"control","weight":-1},{"name":"treatment","weight":2}]}`, true},
+		{"no weight", `{"name":"prompt-v2","variants":[{"name":"control"}]}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "experiment.json")
+			if err := os.WriteFile(path, []byte(tt.json), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := Load(path); (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
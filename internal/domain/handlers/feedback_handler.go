@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/services"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+)
+
+// maxFeedbackBytes is the maximum size of a feedback request body
+const maxFeedbackBytes = 16 << 10
+
+// FeedbackHandler handles HTTP requests for classification feedback
+type FeedbackHandler struct {
+	service   *services.FeedbackService
+	localizer *localization.Localizer
+}
+
+// NewFeedbackHandler creates a new feedback handler
+func NewFeedbackHandler(service *services.FeedbackService, localizer *localization.Localizer) *FeedbackHandler {
+	return &FeedbackHandler{
+		service:   service,
+		localizer: localizer,
+	}
+}
+
+// HandleSubmit processes the JSON feedback request
+func (h *FeedbackHandler) HandleSubmit(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.FeedbackResponse, error) {
+	var request models.FeedbackRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFeedbackBytes)).Decode(&request); err != nil {
+		return &models.FeedbackResponse{
+			Success: false,
+			Error:   h.localizer.GetErrorMessage(h.localizer.LanguageFromHeader(r.Header.Get("Accept-Language")), "invalid_feedback"),
+		}, nil
+	}
+
+	if request.Language == "" {
+		request.Language = "de" // Default to German
+	}
+
+	return h.service.Submit(ctx, &request)
+}
+
+// HandleAccuracy returns the aggregated feedback per item category
+func (h *FeedbackHandler) HandleAccuracy(ctx context.Context, r *http.Request) (*models.FeedbackResponse, error) {
+	return h.service.Accuracy(ctx)
+}
+
+// WriteJSONResponse writes a JSON response to the HTTP response writer
+func (h *FeedbackHandler) WriteJSONResponse(w http.ResponseWriter, response *models.FeedbackResponse, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/services"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+)
+
+// DeviceIDHeader is the request header carrying the anonymous device ID the history is stored for
+const DeviceIDHeader = "X-Device-ID"
+
+// HistoryHandler handles HTTP requests for the classification history
+type HistoryHandler struct {
+	service   *services.HistoryService
+	localizer *localization.Localizer
+}
+
+// NewHistoryHandler creates a new history handler
+func NewHistoryHandler(service *services.HistoryService, localizer *localization.Localizer) *HistoryHandler {
+	return &HistoryHandler{
+		service:   service,
+		localizer: localizer,
+	}
+}
+
+// HandleList returns the recent items of the device
+func (h *HistoryHandler) HandleList(ctx context.Context, r *http.Request) (*models.HistoryResponse, error) {
+	return h.service.List(ctx, r.Header.Get(DeviceIDHeader), h.language(r))
+}
+
+// HandleDelete removes the history of the device
+func (h *HistoryHandler) HandleDelete(ctx context.Context, r *http.Request) (*models.HistoryResponse, error) {
+	return h.service.Delete(ctx, r.Header.Get(DeviceIDHeader), h.language(r))
+}
+
+// language returns the language of the request from the language query parameter or the Accept-Language header
+func (h *HistoryHandler) language(r *http.Request) string {
+	if language := r.URL.Query().Get("language"); h.localizer.IsLanguageSupported(language) {
+		return language
+	}
+	return h.localizer.LanguageFromHeader(r.Header.Get("Accept-Language"))
+}
+
+// WriteJSONResponse writes a JSON response to the HTTP response writer
+func (h *HistoryHandler) WriteJSONResponse(w http.ResponseWriter, response *models.HistoryResponse, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
@@ -5,29 +5,52 @@ import (
 	"encoding/json"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/services"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/auth"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/tracing"
 	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // WasteSortingHandler handles HTTP requests for waste sorting
 type WasteSortingHandler struct {
 	service   *services.WasteSortingService
 	localizer *localization.Localizer
+	logger    logging.Logger
+	tracer    tracing.Tracer
 }
 
 // NewWasteSortingHandler creates a new waste sorting handler
-func NewWasteSortingHandler(service *services.WasteSortingService, localizer *localization.Localizer) *WasteSortingHandler {
+func NewWasteSortingHandler(service *services.WasteSortingService, localizer *localization.Localizer, logger logging.Logger, tracer tracing.Tracer) *WasteSortingHandler {
 	return &WasteSortingHandler{
 		service:   service,
 		localizer: localizer,
+		logger:    logger,
+		tracer:    tracer,
 	}
 }
 
 // HandleRequest processes the waste sorting HTTP request
 func (h *WasteSortingHandler) HandleRequest(ctx context.Context, r *http.Request) (*models.WasteSortingResponse, error) {
 	// Parse multipart form
+	_, span := h.tracer.Start(ctx, "Parse request")
+	span.SetAttributes(attribute.Int64("http.request.body.size", r.ContentLength))
 	err := r.ParseMultipartForm(10 << 20) // 10 MB max
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.Int("image.count", len(r.MultipartForm.File["image"])))
+	}
+	span.End()
+	if err != nil {
+		h.logger.Info(ctx, map[string]interface{}{
+			"message": "Rejected request with invalid form",
+			"error":   err.Error(),
+		})
 		return &models.WasteSortingResponse{
 			Success: false,
 			Error:   "Failed to parse form",
@@ -37,37 +60,88 @@ func (h *WasteSortingHandler) HandleRequest(ctx context.Context, r *http.Request
 	// Extract form fields
 	postalCode := r.FormValue("postal_code")
 	recaptchaCode := r.FormValue("recaptcha_code")
+	if captchaCode := r.FormValue("captcha_code"); captchaCode != "" {
+		recaptchaCode = captchaCode
+	}
+	captchaProvider := r.FormValue("captcha_provider")
 	language := r.FormValue("language")
+	query := r.FormValue("query")
+	deviceID := r.Header.Get(DeviceIDHeader)
 
 	if !h.localizer.IsLanguageSupported(language) {
 		language = "de" // Default to German
 	}
 
-	// Validate required fields
-	if postalCode == "" || recaptchaCode == "" {
+	// Validate required fields, authenticated API clients only solve a captcha if they are configured to
+	client := auth.ClientFromContext(ctx)
+	captchaRequired := client == nil || client.CaptchaProvider != ""
+	if postalCode == "" || (recaptchaCode == "" && captchaRequired) {
+		h.logger.Info(ctx, map[string]interface{}{
+			"message":          "Rejected request with missing fields",
+			"reason":           "missing_fields",
+			"postal_code":      postalCode != "",
+			"captcha_required": captchaRequired,
+		})
 		return &models.WasteSortingResponse{
 			Success: false,
 			Error:   h.localizer.GetErrorMessage(language, "missing_fields"),
 		}, nil
 	}
 
-	// Get uploaded file
-	file, fileHeader, err := r.FormFile("image")
-	if err != nil {
+	// Get uploaded files, multi-part items may be sent as several image parts
+	fileHeaders := r.MultipartForm.File["image"]
+	if len(fileHeaders) == 0 && query != "" {
+		// Text query mode: the item is described by name instead of a photo
+		return h.service.ProcessTextQuery(ctx, &models.WasteSortingRequest{
+			PostalCode:      postalCode,
+			RecaptchaCode:   recaptchaCode,
+			CaptchaProvider: captchaProvider,
+			Language:        language,
+			Query:           query,
+			DeviceID:        deviceID,
+		})
+	}
+	if len(fileHeaders) == 0 {
+		h.logger.Info(ctx, map[string]interface{}{
+			"message": "Rejected request without images or item description",
+			"reason":  "invalid_image",
+		})
 		return &models.WasteSortingResponse{
 			Success: false,
 			Error:   h.localizer.GetErrorMessage(language, "invalid_image"),
 		}, nil
 	}
-	defer file.Close()
+
+	images := make([]models.ImageUpload, 0, len(fileHeaders))
+	defer func() {
+		for _, image := range images {
+			image.File.Close()
+		}
+	}()
+	for _, fileHeader := range fileHeaders {
+		file, err := fileHeader.Open()
+		if err != nil {
+			h.logger.Warning(ctx, map[string]interface{}{
+				"message": "Failed to open uploaded image",
+				"reason":  "invalid_image",
+				"error":   err.Error(),
+			})
+			return &models.WasteSortingResponse{
+				Success: false,
+				Error:   h.localizer.GetErrorMessage(language, "invalid_image"),
+			}, nil
+		}
+		images = append(images, models.ImageUpload{File: file, Header: fileHeader})
+	}
 
 	// Create request model
 	request := &models.WasteSortingRequest{
-		PostalCode:    postalCode,
-		RecaptchaCode: recaptchaCode,
-		Language:      language,
-		ImageFile:     file,
-		ImageHeader:   fileHeader,
+		PostalCode:      postalCode,
+		RecaptchaCode:   recaptchaCode,
+		CaptchaProvider: captchaProvider,
+		Language:        language,
+		Images:          images,
+		DeviceID:        deviceID,
 	}
 
 	// Process the request
@@ -3,23 +3,51 @@ package domain
 import (
 	"context"
 	"fmt"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/middleware"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/config"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/container"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/metrics"
+	"github.com/DeryabinSergey/waste-tips-backend/libs/tracer"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"net/http"
 	"strings"
+	"time"
 )
 
+// TraceIDHeader is the response header carrying the trace ID of the request, to find its trace from the frontend
+const TraceIDHeader = "X-Trace-ID"
+
+// maxFormFieldsBytes is the size allowed for the form fields and part headers of a request next to its images
+const maxFormFieldsBytes = 1 << 20
+
 // Invoke is the main entry point for Google Cloud Functions
 func Invoke(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-	w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
+	// CORS is handled before the container is created, so preflight requests stay cheap
+	cors(config.LoadConfig())(http.HandlerFunc(invoke)).ServeHTTP(w, r)
+}
 
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
+// NewHandler returns a handler serving all requests with the prebuilt container, like Invoke does
+// with a container created per request. The container is not closed by the handler.
+func NewHandler(appContainer *container.Container) http.Handler {
+	return cors(appContainer.Config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serve(w, r, appContainer)
+	}))
+}
+
+// cors applies the configured CORS policy
+func cors(cfg *config.Config) middleware.Middleware {
+	return middleware.CORS(middleware.CORSPolicy{
+		AllowedOrigins: cfg.CORSAllowedOrigins,
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodOptions},
+		AllowedHeaders: cfg.CORSAllowedHeaders,
+		ExposedHeaders: []string{"Retry-After", middleware.IdempotentReplayedHeader, TraceIDHeader},
+		MaxAge:         cfg.CORSMaxAge,
+	})
+}
 
+// invoke initializes the application and handles the request
+func invoke(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	// Check if the request context is valid
 	if ctx.Err() != nil {
@@ -42,12 +70,127 @@ func Invoke(w http.ResponseWriter, r *http.Request) {
 		_ = appContainer.Logger.Close(ctx)
 	}(ctx)
 
-	spanCtx, span := appContainer.Tracer.Start(ctx, "Application Invoke")
+	serve(w, r, appContainer)
+}
+
+// serve traces the request and passes it through the middlewares to the router.
+// The trace continues the trace context of the caller, such as the frontend or a load balancer.
+func serve(w http.ResponseWriter, r *http.Request, appContainer *container.Container) {
+	ctx := tracer.Propagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	spanCtx, span := appContainer.Tracer.Start(ctx, "Application Invoke", trace.WithSpanKind(trace.SpanKindServer))
 	defer span.End()
 	r = r.WithContext(spanCtx)
 
+	if traceID := span.SpanContext().TraceID(); traceID.IsValid() {
+		w.Header().Set(TraceIDHeader, traceID.String())
+	}
+
+	middlewares := []middleware.Middleware{
+		middleware.Authenticate(appContainer.KeyStore, appContainer.Localizer, appContainer.Logger),
+	}
+	// Duplicates are answered before rate limiting, so retries of a completed request do not use up the quota
+	if appContainer.Deduplicator != nil {
+		middlewares = append(middlewares, middleware.Idempotency(appContainer.Deduplicator, appContainer.Config.MaxImagesBytes+maxFormFieldsBytes, appContainer.IPResolver, appContainer.Localizer, appContainer.Logger))
+	}
+	if appContainer.RateLimiter != nil {
+		middlewares = append(middlewares, middleware.RateLimit(appContainer.RateLimiter, appContainer.Localizer, appContainer.Logger))
+	}
+
+	// Requests rejected by a middleware are counted with the route they were meant for and no language
+	start := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+	var language string
+	middleware.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		language = route(w, r, appContainer)
+	}), middlewares...).ServeHTTP(recorder, r)
+	metrics.RecordRequest(spanCtx, routeOf(r, appContainer), recorder.statusCode, language, time.Since(start))
+}
+
+// statusRecorder records the status code written to the response for the request metrics
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+// WriteHeader records the status code and writes it to the response
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Routes of the application, used as the route label of the request metrics
+const (
+	routeFeedback         = "feedback"
+	routeFeedbackAccuracy = "feedback_accuracy"
+	routeWasteSorting     = "waste_sorting"
+	routeHistory          = "history"
+	routeNotFound         = "not_found"
+)
+
+// routeOf returns the route matching the method, path and content type of the request
+func routeOf(r *http.Request, appContainer *container.Container) string {
 	switch {
+	case r.URL.Path == "/feedback" && appContainer.FeedbackHandler != nil && r.Method == http.MethodPost:
+		return routeFeedback
+	case r.URL.Path == "/feedback/accuracy" && appContainer.FeedbackHandler != nil && r.Method == http.MethodGet:
+		return routeFeedbackAccuracy
 	case r.Method == http.MethodPost && strings.Contains(r.Header.Get("Content-Type"), "multipart/form-data"):
+		return routeWasteSorting
+	case r.URL.Path == "/history" && appContainer.HistoryHandler != nil && (r.Method == http.MethodGet || r.Method == http.MethodDelete):
+		return routeHistory
+	default:
+		return routeNotFound
+	}
+}
+
+// route dispatches the request to the handler of its route.
+// It returns the language of waste sorting requests for the request metrics.
+func route(w http.ResponseWriter, r *http.Request, appContainer *container.Container) (language string) {
+	spanCtx := r.Context()
+
+	switch routeOf(r, appContainer) {
+	case routeFeedback:
+		// Handle feedback on a classification
+		appContainer.Logger.Info(spanCtx, map[string]interface{}{
+			"message": "Processing feedback request",
+			"method":  r.Method,
+			"path":    r.URL.Path,
+		})
+
+		response, err := appContainer.FeedbackHandler.HandleSubmit(spanCtx, w, r)
+		if err != nil {
+			appContainer.Logger.Error(spanCtx, map[string]interface{}{
+				"message": "Failed to process feedback request",
+				"error":   err.Error(),
+			})
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		statusCode := http.StatusOK
+		if !response.Success {
+			statusCode = http.StatusBadRequest
+		}
+
+		appContainer.FeedbackHandler.WriteJSONResponse(w, response, statusCode)
+
+	case routeFeedbackAccuracy:
+		// Handle aggregated feedback for prompt tuning, which is only available to API clients
+		middleware.RequireClient(appContainer.Localizer, appContainer.Logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response, err := appContainer.FeedbackHandler.HandleAccuracy(spanCtx, r)
+			if err != nil {
+				appContainer.Logger.Error(spanCtx, map[string]interface{}{
+					"message": "Failed to aggregate feedback",
+					"error":   err.Error(),
+				})
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			appContainer.FeedbackHandler.WriteJSONResponse(w, response, http.StatusOK)
+		})).ServeHTTP(w, r)
+
+	case routeWasteSorting:
 		// Handle waste sorting request
 		appContainer.Logger.Info(spanCtx, map[string]interface{}{
 			"message": "Processing waste sorting request",
@@ -73,12 +216,50 @@ func Invoke(w http.ResponseWriter, r *http.Request) {
 
 		appContainer.WasteSortingHandler.WriteJSONResponse(w, response, statusCode)
 
+		// The handler has parsed the form, unsupported languages are answered in German like it does
+		language = r.FormValue("language")
+		if !appContainer.Localizer.IsLanguageSupported(language) {
+			language = "de"
+		}
+
 		appContainer.Logger.Info(spanCtx, map[string]interface{}{
-			"message":     "Waste sorting request processed successfully",
-			"success":     response.Success,
-			"status_code": statusCode,
+			"message":        "Waste sorting request processed successfully",
+			"success":        response.Success,
+			"status_code":    statusCode,
+			"prompt_version": response.PromptVersion,
+			"variant":        response.Variant,
 		})
 
+	case routeHistory:
+		// Handle history request
+		appContainer.Logger.Info(spanCtx, map[string]interface{}{
+			"message": "Processing history request",
+			"method":  r.Method,
+			"path":    r.URL.Path,
+		})
+
+		handle := appContainer.HistoryHandler.HandleList
+		if r.Method == http.MethodDelete {
+			handle = appContainer.HistoryHandler.HandleDelete
+		}
+
+		response, err := handle(spanCtx, r)
+		if err != nil {
+			appContainer.Logger.Error(spanCtx, map[string]interface{}{
+				"message": "Failed to process history request",
+				"error":   err.Error(),
+			})
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		statusCode := http.StatusOK
+		if !response.Success {
+			statusCode = http.StatusBadRequest
+		}
+
+		appContainer.HistoryHandler.WriteJSONResponse(w, response, statusCode)
+
 	default:
 		appContainer.Logger.Warning(spanCtx, map[string]interface{}{
 			"message":      "Unsupported request",
@@ -87,4 +268,6 @@ func Invoke(w http.ResponseWriter, r *http.Request) {
 		})
 		http.Error(w, "Not found", http.StatusNotFound)
 	}
+
+	return language
 }
@@ -0,0 +1,554 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/services"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/auth"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/cache"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/config"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/container"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging/loggingtest"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/ratelimit"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/tracing/tracingtest"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// testOrigin is the frontend origin allowed by the test configuration
+const testOrigin = "https://app.example.org"
+
+// testAPIKey is the API key of the partner client in the test key store
+const testAPIKey = "partner-key"
+
+type classifierFunc func(ctx context.Context, input *services.ClassificationInput) (*models.Classification, error)
+
+func (f classifierFunc) Classify(ctx context.Context, input *services.ClassificationInput) (*models.Classification, error) {
+	return f(ctx, input)
+}
+
+// bottleClassifier classifies everything as a glass bottle
+var bottleClassifier = classifierFunc(func(context.Context, *services.ClassificationInput) (*models.Classification, error) {
+	return &models.Classification{
+		Item:          "glass bottle",
+		Category:      "glass",
+		Components:    []models.ClassifiedComponent{{Name: "bottle", Material: "glass", Bin: "Glascontainer"}},
+		PromptVersion: "classification@v1",
+	}, nil
+})
+
+// languageTranslator renders the bin of the first component with the language
+type languageTranslator struct{}
+
+func (languageTranslator) Translate(_ context.Context, classification *models.Classification, language string) (string, error) {
+	return "<p>" + language + ": " + classification.Components[0].Bin + "</p>", nil
+}
+
+// captchaVerifier accepts the token "human"
+type captchaVerifier struct{}
+
+func (captchaVerifier) VerifyToken(_ context.Context, token string) (bool, error) {
+	return token == "human", nil
+}
+
+// testBrowserAPIKey is the public API key of a browser app that has to solve a reCAPTCHA
+const testBrowserAPIKey = "browser-key"
+
+// keyStore knows the partner client with testAPIKey and the browser app with testBrowserAPIKey
+type keyStore struct{}
+
+func (keyStore) Lookup(_ context.Context, keyHash string) (*auth.Client, error) {
+	switch keyHash {
+	case auth.HashKey(testAPIKey):
+		return &auth.Client{ID: "partner"}, nil
+	case auth.HashKey(testBrowserAPIKey):
+		return &auth.Client{ID: "browser-app", CaptchaProvider: services.CaptchaProviderRecaptcha}, nil
+	}
+	return nil, nil
+}
+
+// yogurtEAN is the barcode on the yogurt cup of the sample evaluation dataset
+const yogurtEAN = "4006381333931"
+
+// productCatalog knows the yogurt cup
+type productCatalog struct{}
+
+func (productCatalog) Lookup(_ context.Context, ean string) (*models.Product, error) {
+	if ean == yogurtEAN {
+		return &models.Product{EAN: ean, Name: "Yogurt", Packaging: []models.PackagingComponent{{Component: "cup", Material: "PP"}}}, nil
+	}
+	return nil, nil
+}
+
+// historyStore keeps the history in memory and has no feedback
+type historyStore struct {
+	entries []*models.HistoryEntry
+}
+
+func (s *historyStore) Save(_ context.Context, entry *models.HistoryEntry) error {
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+func (s *historyStore) Get(_ context.Context, id string) (*models.HistoryEntry, error) {
+	for _, entry := range s.entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *historyStore) List(_ context.Context, deviceID string, _ int) ([]*models.HistoryEntry, error) {
+	var entries []*models.HistoryEntry
+	for _, entry := range s.entries {
+		if entry.DeviceID == deviceID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (s *historyStore) Delete(context.Context, string) error {
+	return nil
+}
+
+func (s *historyStore) SaveFeedback(context.Context, *models.Feedback) error {
+	return nil
+}
+
+func (s *historyStore) FeedbackAccuracy(context.Context) ([]*models.CategoryAccuracy, error) {
+	return []*models.CategoryAccuracy{}, nil
+}
+
+// testServer serves the application with fake dependencies and records its logs, spans and history
+type testServer struct {
+	*httptest.Server
+	logger  *loggingtest.Logger
+	tracer  *tracingtest.Tracer
+	history *historyStore
+}
+
+// testConfig allows the test origin and ignores idempotency keys
+func testConfig() *config.Config {
+	cfg := config.LoadConfig()
+	cfg.CORSAllowedOrigins = []string{testOrigin}
+	cfg.IdempotencyBackend = "none"
+	return cfg
+}
+
+// newTestServer serves the application with the options, which replace the defaults of the test server
+func newTestServer(t *testing.T, opts ...container.Option) *testServer {
+	t.Helper()
+	server := &testServer{
+		logger:  loggingtest.NewLogger(),
+		tracer:  tracingtest.NewTracer(),
+		history: &historyStore{},
+	}
+	opts = append([]container.Option{
+		container.WithConfig(testConfig()),
+		container.WithLogger(server.logger),
+		container.WithTracer(server.tracer),
+		container.WithRecaptchaService(captchaVerifier{}),
+		container.WithCatalog(productCatalog{}),
+		container.WithCacheStore(cache.NewMemoryStore(10)),
+		container.WithRateLimitStore(ratelimit.NewMemoryStore()),
+		container.WithHistoryRepository(server.history),
+		container.WithKeyStore(keyStore{}),
+		container.WithClock(func() time.Time { return time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC) }),
+	}, opts...)
+
+	appContainer, err := container.NewContainer(context.Background(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Server = httptest.NewServer(NewHandler(appContainer))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// samplePNG is the image sent by waste sorting requests
+const samplePNG = "services/testdata/sample.png"
+
+// wasteSortingRequest builds a multipart waste sorting request with the fields and an image
+func wasteSortingRequest(t *testing.T, url string, fields map[string]string) *http.Request {
+	t.Helper()
+	return wasteSortingImageRequest(t, url, fields, samplePNG)
+}
+
+// wasteSortingImageRequest builds a multipart waste sorting request with the fields and the PNG images at paths
+func wasteSortingImageRequest(t *testing.T, url string, fields map[string]string, paths ...string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	for _, path := range paths {
+		image, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part, err := form.CreatePart(map[string][]string{
+			"Content-Disposition": {`form-data; name="image"; filename="sample.png"`},
+			"Content-Type":        {"image/png"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(image)
+	}
+	form.Close()
+
+	r, err := http.NewRequest(http.MethodPost, url, &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	return r
+}
+
+// do sends the request and decodes the JSON response
+func do(t *testing.T, r *http.Request) (*http.Response, *models.WasteSortingResponse) {
+	t.Helper()
+	response, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var body models.WasteSortingResponse
+	if strings.HasPrefix(response.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return response, &body
+}
+
+func TestInvokeAnswersPreflight(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", testOrigin)
+
+	tests := []struct {
+		name       string
+		origin     string
+		wantStatus int
+		wantOrigin string
+	}{
+		{"allowed origin", testOrigin, http.StatusNoContent, testOrigin},
+		{"other origin", "https://evil.example.org", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "/", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			w := httptest.NewRecorder()
+
+			Invoke(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+		})
+	}
+}
+
+func TestHandlerRoutes(t *testing.T) {
+	server := newTestServer(t, container.WithClassifier(bottleClassifier), container.WithTranslator(languageTranslator{}))
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		wantStatus  int
+	}{
+		{"unknown path", http.MethodGet, "/", "", http.StatusNotFound},
+		{"json waste sorting request", http.MethodPost, "/", "application/json", http.StatusNotFound},
+		{"history without device ID", http.MethodGet, "/history", "", http.StatusBadRequest},
+		{"history with unsupported method", http.MethodPut, "/history", "", http.StatusNotFound},
+		{"feedback accuracy without API key", http.MethodGet, "/feedback/accuracy", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader("{}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			if response, _ := do(t, r); response.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", response.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+
+	t.Run("feedback accuracy with API key", func(t *testing.T) {
+		r, err := http.NewRequest(http.MethodGet, server.URL+"/feedback/accuracy", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("X-API-Key", testAPIKey)
+
+		if response, _ := do(t, r); response.StatusCode != http.StatusOK {
+			t.Errorf("status = %d, want %d", response.StatusCode, http.StatusOK)
+		}
+	})
+}
+
+func TestHandlerLocalizesValidationErrors(t *testing.T) {
+	// Every language sends every invalid request, which is more than the rate limit allows
+	cfg := testConfig()
+	cfg.MaxImages = 2
+	cfg.MaxImagesBytes = 200
+	cfg.RateLimitIP = 0
+	server := newTestServer(t, container.WithConfig(cfg), container.WithClassifier(bottleClassifier), container.WithTranslator(languageTranslator{}))
+	localizer := localization.NewLocalizer()
+
+	tests := []struct {
+		name        string
+		fields      map[string]string
+		images      []string
+		deviceID    string
+		wantMessage string
+		wantLog     string
+	}{
+		{"missing fields", map[string]string{"recaptcha_code": "human"}, []string{samplePNG}, "", "missing_fields", "Rejected request with missing fields"},
+		{"invalid postal code", map[string]string{"postal_code": "123", "recaptcha_code": "human"}, []string{samplePNG}, "", "invalid_postal_code", "Rejected request with invalid postal code"},
+		{"no image or query", map[string]string{"postal_code": "10115", "recaptcha_code": "human"}, nil, "", "invalid_image", "Rejected request without images or item description"},
+		{"too many images", map[string]string{"postal_code": "10115", "recaptcha_code": "human"}, []string{samplePNG, samplePNG, samplePNG}, "", "too_many_images", "Rejected request with too many images"},
+		{"images too large", map[string]string{"postal_code": "10115", "recaptcha_code": "human"}, []string{samplePNG, samplePNG}, "", "images_too_large", "Rejected request with too large images"},
+		{"query too long", map[string]string{"postal_code": "10115", "recaptcha_code": "human", "query": strings.Repeat("a", 201)}, nil, "", "invalid_query", "Rejected request with invalid item description"},
+		{"invalid device id", map[string]string{"postal_code": "10115", "recaptcha_code": "human"}, []string{samplePNG}, "not a device id", "invalid_device_id", "Rejected request with invalid device ID"},
+	}
+
+	for _, tt := range tests {
+		for _, language := range []string{"de", "en", "tr", "ar", "uk"} {
+			t.Run(tt.name+"/"+language, func(t *testing.T) {
+				fields := map[string]string{"language": language}
+				for name, value := range tt.fields {
+					fields[name] = value
+				}
+				r := wasteSortingImageRequest(t, server.URL, fields, tt.images...)
+				if tt.deviceID != "" {
+					r.Header.Set("X-Device-ID", tt.deviceID)
+				}
+
+				response, body := do(t, r)
+				if response.StatusCode != http.StatusBadRequest {
+					t.Errorf("status = %d, want %d", response.StatusCode, http.StatusBadRequest)
+				}
+				if want := localizer.GetErrorMessage(language, tt.wantMessage); body.Error != want {
+					t.Errorf("error = %q, want %q", body.Error, want)
+				}
+			})
+		}
+
+		if !slices.Contains(server.logger.Messages(), tt.wantLog) {
+			t.Errorf("logged %q, want %q", server.logger.Messages(), tt.wantLog)
+		}
+	}
+}
+
+func TestHandlerRejectsFailedCaptcha(t *testing.T) {
+	server := newTestServer(t, container.WithClassifier(bottleClassifier), container.WithTranslator(languageTranslator{}))
+
+	response, body := do(t, wasteSortingRequest(t, server.URL, map[string]string{
+		"postal_code":    "10115",
+		"recaptcha_code": "bot",
+		"language":       "en",
+	}))
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusBadRequest)
+	}
+	if want := localization.NewLocalizer().GetErrorMessage("en", "recaptcha_failed"); body.Error != want {
+		t.Errorf("error = %q, want %q", body.Error, want)
+	}
+	if !slices.Contains(server.logger.Messages(), "Rejected request with invalid captcha") {
+		t.Errorf("logged %q, want the captcha rejection", server.logger.Messages())
+	}
+}
+
+func TestHandlerSkipsCaptchaForAPIClients(t *testing.T) {
+	server := newTestServer(t, container.WithClassifier(bottleClassifier), container.WithTranslator(languageTranslator{}))
+	localizer := localization.NewLocalizer()
+
+	tests := []struct {
+		name        string
+		apiKey      string
+		captcha     string
+		wantStatus  int
+		wantMessage string
+	}{
+		{"anonymous without captcha", "", "", http.StatusBadRequest, "missing_fields"},
+		{"api client without captcha", testAPIKey, "", http.StatusOK, ""},
+		{"api client with failed captcha", testAPIKey, "bot", http.StatusOK, ""},
+		{"unknown key without captcha", "guessed-key", "", http.StatusUnauthorized, "invalid_api_key"},
+		{"browser app without captcha", testBrowserAPIKey, "", http.StatusBadRequest, "missing_fields"},
+		{"browser app with failed captcha", testBrowserAPIKey, "bot", http.StatusBadRequest, "recaptcha_failed"},
+		{"browser app with captcha", testBrowserAPIKey, "human", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := map[string]string{"postal_code": "10115", "language": "en"}
+			if tt.captcha != "" {
+				fields["recaptcha_code"] = tt.captcha
+			}
+			r := wasteSortingRequest(t, server.URL, fields)
+			// Requests rejected by the middlewares are answered in the language of the header
+			r.Header.Set("Accept-Language", "en")
+			if tt.apiKey != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.apiKey)
+			}
+
+			response, body := do(t, r)
+			if response.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", response.StatusCode, tt.wantStatus)
+			}
+			if tt.wantMessage != "" {
+				if want := localizer.GetErrorMessage("en", tt.wantMessage); body.Error != want {
+					t.Errorf("error = %q, want %q", body.Error, want)
+				}
+			} else if !body.Success {
+				t.Errorf("request failed with %q, want success", body.Error)
+			}
+		})
+	}
+}
+
+func TestHandlerReportsModelFailure(t *testing.T) {
+	server := newTestServer(t, container.WithClassifier(classifierFunc(func(context.Context, *services.ClassificationInput) (*models.Classification, error) {
+		return nil, errors.New("model unavailable")
+	})), container.WithTranslator(languageTranslator{}))
+
+	response, body := do(t, wasteSortingRequest(t, server.URL, map[string]string{
+		"postal_code":    "10115",
+		"recaptcha_code": "human",
+		"language":       "de",
+	}))
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusBadRequest)
+	}
+	if want := localization.NewLocalizer().GetErrorMessage("de", "processing_error"); body.Error != want {
+		t.Errorf("error = %q, want %q", body.Error, want)
+	}
+
+	failed := false
+	for _, span := range server.tracer.Spans() {
+		failed = failed || span.Name() == "Classify" && span.Status().Code == codes.Error
+	}
+	if !failed {
+		t.Error("no failed Classify span was recorded")
+	}
+	if !slices.Contains(server.logger.Messages(), "Failed to classify images") {
+		t.Errorf("logged %q, want the classification failure", server.logger.Messages())
+	}
+}
+
+func TestHandlerClassifiesImage(t *testing.T) {
+	server := newTestServer(t, container.WithClassifier(bottleClassifier), container.WithTranslator(languageTranslator{}))
+
+	r := wasteSortingRequest(t, server.URL, map[string]string{
+		"postal_code":    "10115",
+		"recaptcha_code": "human",
+		"language":       "en",
+	})
+	r.Header.Set("Origin", testOrigin)
+	r.Header.Set("X-Device-ID", "device-1234")
+
+	response, body := do(t, r)
+	if response.StatusCode != http.StatusOK || !body.Success {
+		t.Fatalf("status = %d, error = %q, want success", response.StatusCode, body.Error)
+	}
+	if body.HTML != "<p>en: Glascontainer</p>" {
+		t.Errorf("html = %q, want the translated classification", body.HTML)
+	}
+	if response.Header.Get("Access-Control-Allow-Origin") != testOrigin {
+		t.Error("response has no CORS header for the allowed origin")
+	}
+	if response.Header.Get(TraceIDHeader) == "" {
+		t.Error("response has no trace ID")
+	}
+	if len(server.history.entries) != 1 || body.ClassificationID != server.history.entries[0].ID {
+		t.Errorf("classification ID %q does not reference the stored history entry", body.ClassificationID)
+	}
+
+	var stages []string
+	for _, span := range server.tracer.Spans() {
+		stages = append(stages, span.Name())
+	}
+	for _, stage := range []string{"Application Invoke", "Parse request", "Verify captcha", "Prepare images", "Classify", "Translate", "Save history"} {
+		if !slices.Contains(stages, stage) {
+			t.Errorf("spans %q have no %q span", stages, stage)
+		}
+	}
+}
+
+func TestHandlerLooksUpBarcodes(t *testing.T) {
+	var products []*models.Product
+	classifier := classifierFunc(func(ctx context.Context, input *services.ClassificationInput) (*models.Classification, error) {
+		products = input.Products
+		return bottleClassifier(ctx, input)
+	})
+	server := newTestServer(t, container.WithClassifier(classifier), container.WithTranslator(languageTranslator{}))
+
+	response, result := do(t, wasteSortingImageRequest(t, server.URL, map[string]string{
+		"postal_code":    "10115",
+		"language":       "en",
+		"recaptcha_code": "human",
+	}, "../../testdata/eval/yogurt-cup.png"))
+	if response.StatusCode != http.StatusOK || !result.Success {
+		t.Fatalf("status = %d, response = %+v, want a classification", response.StatusCode, result)
+	}
+	if len(products) != 1 || products[0].EAN != yogurtEAN {
+		t.Errorf("classified products = %v, want the yogurt of the catalog", products)
+	}
+}
+
+func TestHandlerReplaysGeminiCassette(t *testing.T) {
+	// The cassette holds the classification and translation calls of this request
+	cfg := testConfig()
+	cfg.GeminiCassetteMode = "replay"
+	cfg.GeminiCassettePath = "testdata/gemini.cassette.json"
+	server := newTestServer(t, container.WithConfig(cfg))
+
+	response, body := do(t, wasteSortingRequest(t, server.URL, map[string]string{
+		"postal_code":    "10115",
+		"recaptcha_code": "human",
+		"language":       "en",
+	}))
+
+	if response.StatusCode != http.StatusOK || !body.Success {
+		t.Fatalf("status = %d, error = %q, want success", response.StatusCode, body.Error)
+	}
+	if !strings.Contains(body.HTML, "Glascontainer") {
+		t.Errorf("html = %q, want the recorded translation naming the bin", body.HTML)
+	}
+	if body.PromptVersion != "classification@v1" {
+		t.Errorf("prompt version = %q, want classification@v1", body.PromptVersion)
+	}
+}
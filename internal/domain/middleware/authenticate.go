@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/auth"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Authenticate resolves the API key of the request to a partner client and stores it in the request context.
+// Requests without an API key pass through anonymously and must solve a captcha instead.
+func Authenticate(keyStore auth.KeyStore, localizer *localization.Localizer, logger logging.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			apiKey := auth.APIKey(r)
+			if apiKey == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			client, err := keyStore.Lookup(ctx, auth.HashKey(apiKey))
+			if err != nil {
+				logger.Error(ctx, map[string]interface{}{
+					"message": "Failed to look up API key",
+					"error":   err.Error(),
+				})
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if client == nil {
+				logger.Warning(ctx, map[string]interface{}{
+					"message": "Invalid API key",
+				})
+				writeError(w, localizer.GetErrorMessage(requestLanguage(r, localizer), "invalid_api_key"), http.StatusUnauthorized)
+				return
+			}
+
+			trace.SpanFromContext(ctx).SetAttributes(attribute.String("api_key.id", client.ID))
+
+			if client.Revoked {
+				logger.Warning(ctx, map[string]interface{}{
+					"message":    "Revoked API key",
+					"api_key_id": client.ID,
+				})
+				writeError(w, localizer.GetErrorMessage(requestLanguage(r, localizer), "invalid_api_key"), http.StatusUnauthorized)
+				return
+			}
+
+			if origin := r.Header.Get("Origin"); !client.IsOriginAllowed(origin) {
+				logger.Warning(ctx, map[string]interface{}{
+					"message":    "Origin not allowed for API key",
+					"api_key_id": client.ID,
+					"origin":     origin,
+				})
+				writeError(w, localizer.GetErrorMessage(requestLanguage(r, localizer), "origin_not_allowed"), http.StatusForbidden)
+				return
+			}
+
+			logger.Info(ctx, map[string]interface{}{
+				"message":    "Authenticated API client",
+				"api_key_id": client.ID,
+			})
+
+			next.ServeHTTP(w, r.WithContext(auth.WithClient(ctx, client)))
+		})
+	}
+}
+
+// RequireClient rejects requests that were not authenticated with an API key, for routes that are not meant for anonymous users
+func RequireClient(localizer *localization.Localizer, logger logging.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if auth.ClientFromContext(r.Context()) == nil {
+				logger.Warning(r.Context(), map[string]interface{}{
+					"message": "API key required",
+					"path":    r.URL.Path,
+				})
+				writeError(w, localizer.GetErrorMessage(requestLanguage(r, localizer), "invalid_api_key"), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
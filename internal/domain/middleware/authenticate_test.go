@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/auth"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging/loggingtest"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/tracing/tracingtest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// testClients are the clients of the test key store by API key
+var testClients = map[string]*auth.Client{
+	"partner-key": {ID: "partner"},
+	"revoked-key": {ID: "former-partner", Revoked: true},
+	"browser-key": {ID: "browser-app", AllowedOrigins: []string{"https://app.example.org"}},
+}
+
+// testKeyStore looks up the test clients, the key "broken-key" fails
+type testKeyStore struct{}
+
+func (testKeyStore) Lookup(_ context.Context, keyHash string) (*auth.Client, error) {
+	if keyHash == auth.HashKey("broken-key") {
+		return nil, errors.New("key store unavailable")
+	}
+	for key, client := range testClients {
+		if auth.HashKey(key) == keyHash {
+			return client, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiKey     string
+		origin     string
+		wantStatus int
+		wantClient string
+		wantLog    string
+	}{
+		{"no key", "", "", http.StatusOK, "", ""},
+		{"valid key", "partner-key", "", http.StatusOK, "partner", "Authenticated API client"},
+		{"unknown key", "guessed-key", "", http.StatusUnauthorized, "", "Invalid API key"},
+		{"revoked key", "revoked-key", "", http.StatusUnauthorized, "", "Revoked API key"},
+		{"allowed origin", "browser-key", "https://app.example.org", http.StatusOK, "browser-app", "Authenticated API client"},
+		{"other origin", "browser-key", "https://evil.example.org", http.StatusForbidden, "", "Origin not allowed for API key"},
+		{"key store failure", "broken-key", "", http.StatusInternalServerError, "", "Failed to look up API key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := loggingtest.NewLogger()
+			var client *auth.Client
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				client = auth.ClientFromContext(r.Context())
+			})
+			handler := Authenticate(testKeyStore{}, localization.NewLocalizer(), logger)(next)
+
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.apiKey != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.apiKey)
+			}
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			gotClient := ""
+			if client != nil {
+				gotClient = client.ID
+			}
+			if gotClient != tt.wantClient {
+				t.Errorf("client = %q, want %q", gotClient, tt.wantClient)
+			}
+			if tt.wantLog != "" && !slices.Contains(logger.Messages(), tt.wantLog) {
+				t.Errorf("logged %q, want %q", logger.Messages(), tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRecordsKeyID(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiKey string
+		wantID string
+	}{
+		{"valid key", "partner-key", "partner"},
+		{"revoked key", "revoked-key", "former-partner"},
+		{"unknown key", "guessed-key", ""},
+		{"no key", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer := tracingtest.NewTracer()
+			handler := Authenticate(testKeyStore{}, localization.NewLocalizer(), loggingtest.NewLogger())(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+			ctx, span := tracer.Start(context.Background(), "Application Invoke")
+			r := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+			if tt.apiKey != "" {
+				r.Header.Set("X-API-Key", tt.apiKey)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			span.End()
+
+			var id string
+			for _, attr := range tracer.Spans()[0].Attributes() {
+				if attr.Key == attribute.Key("api_key.id") {
+					id = attr.Value.AsString()
+				}
+			}
+			if id != tt.wantID {
+				t.Errorf("api_key.id = %q, want %q", id, tt.wantID)
+			}
+		})
+	}
+}
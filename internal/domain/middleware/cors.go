@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CORSPolicy configures which origins may call the API from a browser
+type CORSPolicy struct {
+	// AllowedOrigins holds exact origins, wildcard subdomain patterns like https://*.example.de, or * for any origin
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	MaxAge         int
+}
+
+// CORS answers preflight requests and reflects allowed origins with Vary: Origin.
+// Preflights from disallowed origins are rejected with 403 Forbidden, other requests from
+// disallowed origins are served without CORS headers, so browsers do not expose the response.
+func CORS(policy CORSPolicy) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+
+			origin := r.Header.Get("Origin")
+			allowed := origin != "" && policy.isOriginAllowed(origin)
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+				if !allowed {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(policy.MaxAge))
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				if len(policy.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+				}
+			}
+
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isOriginAllowed matches the origin against the allow-list
+func (p CORSPolicy) isOriginAllowed(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, allowed := range p.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+
+		if matchesWildcardOrigin(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWildcardOrigin matches the origin against a pattern like https://*.example.de, which allows
+// any subdomain of example.de on any port, but not example.de itself. A port in the pattern must match.
+func matchesWildcardOrigin(pattern, origin string) bool {
+	scheme, domain, found := strings.Cut(pattern, "://*.")
+	if !found {
+		return false
+	}
+	domain, port, _ := strings.Cut(domain, ":")
+
+	originURL, err := url.Parse(origin)
+	if err != nil || originURL.Scheme != scheme {
+		return false
+	}
+	if port != "" && originURL.Port() != port {
+		return false
+	}
+
+	return strings.HasSuffix(originURL.Hostname(), "."+domain)
+}
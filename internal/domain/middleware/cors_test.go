@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSPolicyIsOriginAllowed(t *testing.T) {
+	policy := CORSPolicy{AllowedOrigins: []string{"https://app.example.de", "https://*.example.com", "http://*.local.test:8080"}}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.de", true},
+		{"https://APP.example.de", true},
+		{"https://other.example.de", false},
+		{"https://app.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://app.example.com:8443", true},
+		{"https://example.com", false},
+		{"http://app.example.com", false},
+		{"https://app.example.com.evil.org", false},
+		{"https://evilexample.com", false},
+		{"http://app.local.test:8080", true},
+		{"http://app.local.test:9090", false},
+		{"http://app.local.test", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if got := policy.isOriginAllowed(tt.origin); got != tt.want {
+				t.Errorf("isOriginAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORSAnswersPreflight(t *testing.T) {
+	handler := CORS(CORSPolicy{
+		AllowedOrigins: []string{"https://*.example.com"},
+		AllowedMethods: []string{http.MethodPost},
+		AllowedHeaders: []string{"Content-Type"},
+		MaxAge:         600,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("preflight reached the handler")
+	}))
+
+	tests := []struct {
+		origin     string
+		wantStatus int
+		wantOrigin string
+	}{
+		{"https://app.example.com:8443", http.StatusNoContent, "https://app.example.com:8443"},
+		{"https://app.example.org", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodOptions, "/", nil)
+			r.Header.Set("Origin", tt.origin)
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+		})
+	}
+}
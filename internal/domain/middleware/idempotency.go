@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/handlers"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/auth"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/idempotency"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/ratelimit"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IdempotencyKeyHeader is the request header clients set to make retries of a request safe
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed for a duplicate request
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// Idempotency replays the completed response of a POST request for duplicates with the same Idempotency-Key,
+// or lets them wait for the request in flight, so resubmitted photos are classified only once.
+// Keys are scoped to the API client, or for anonymous clients to the device ID or else the client IP.
+// A key reused for a request with another method, path or body is rejected with 422 Unprocessable Entity.
+// If the response store fails, requests are processed as if they had no key.
+// Bodies of requests with a key are read into memory to fingerprint them, so they are limited to maxBodyBytes.
+func Idempotency(deduplicator *idempotency.Deduplicator, maxBodyBytes int64, ipResolver *ratelimit.IPResolver, localizer *localization.Localizer, logger logging.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(IdempotencyKeyHeader) == "" || r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			fingerprint, err := requestFingerprint(w, r, maxBodyBytes)
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				logger.Info(ctx, map[string]interface{}{
+					"message":   "Rejected too large idempotent request",
+					"reason":    "images_too_large",
+					"max_bytes": maxBytesErr.Limit,
+				})
+				writeError(w, localizer.GetErrorMessage(requestLanguage(r, localizer), "images_too_large"), http.StatusRequestEntityTooLarge)
+				return
+			}
+			if err != nil {
+				logger.Warning(ctx, map[string]interface{}{
+					"message": "Failed to read idempotent request",
+					"error":   err.Error(),
+				})
+				writeError(w, localizer.GetErrorMessage(requestLanguage(r, localizer), "processing_error"), http.StatusBadRequest)
+				return
+			}
+
+			response, replayed, err := deduplicator.Do(ctx, idempotencyKey(r, ipResolver), fingerprint, func(ctx context.Context) *idempotency.Response {
+				recorder := newResponseRecorder()
+				next.ServeHTTP(recorder, r.WithContext(ctx))
+				return recorder.response()
+			})
+			if errors.Is(err, idempotency.ErrKeyReused) {
+				logger.Warning(ctx, map[string]interface{}{
+					"message": "Rejected reused idempotency key",
+					"reason":  "idempotency_key_reused",
+				})
+				writeError(w, localizer.GetErrorMessage(requestLanguage(r, localizer), "idempotency_key_reused"), http.StatusUnprocessableEntity)
+				return
+			}
+			if err != nil {
+				logger.Error(ctx, map[string]interface{}{
+					"message": "Idempotency store failed",
+					"error":   err.Error(),
+				})
+			}
+			if response == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("idempotency.replayed", replayed))
+			if replayed {
+				logger.Info(ctx, map[string]interface{}{
+					"message":     "Replaying idempotent response",
+					"status_code": response.StatusCode,
+				})
+				w.Header().Set(IdempotentReplayedHeader, "true")
+			}
+
+			for name, values := range response.Header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(response.StatusCode)
+			w.Write(response.Body)
+		})
+	}
+}
+
+// idempotencyKey returns the store key of the request scoped to its client.
+// The header value is hashed, so clients cannot choose arbitrary store keys.
+func idempotencyKey(r *http.Request, ipResolver *ratelimit.IPResolver) string {
+	scope := "ip:" + ipResolver.ClientIP(r)
+	if client := auth.ClientFromContext(r.Context()); client != nil {
+		scope = "key:" + client.ID
+	} else if deviceID := r.Header.Get(handlers.DeviceIDHeader); deviceID != "" {
+		scope = "device:" + deviceID
+	}
+
+	hash := sha256.Sum256([]byte(r.Header.Get(IdempotencyKeyHeader)))
+	return scope + ":" + hex.EncodeToString(hash[:])
+}
+
+// requestFingerprint hashes the method, URL and body of the request.
+// The body is read into memory up to maxBodyBytes and restored for the next handler. Clients choose
+// a new multipart boundary for every retry, so it is removed from the body before hashing.
+func requestFingerprint(w http.ResponseWriter, r *http.Request, maxBodyBytes int64) (string, error) {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+
+	if r.Body != nil {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		r.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && params["boundary"] != "" {
+			body = bytes.ReplaceAll(body, []byte(params["boundary"]), nil)
+		}
+		hash.Write(body)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// responseRecorder captures a response so it can be stored and written to every waiting request
+type responseRecorder struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{
+		header:     http.Header{},
+		statusCode: http.StatusOK,
+	}
+}
+
+// Header returns the response headers
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+// Write captures the response body
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+// WriteHeader captures the status code
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+}
+
+// response returns the captured response
+func (r *responseRecorder) response() *idempotency.Response {
+	return &idempotency.Response{
+		StatusCode: r.statusCode,
+		Header:     r.header,
+		Body:       r.body.Bytes(),
+	}
+}
@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/cache"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/idempotency"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging/loggingtest"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/ratelimit"
+)
+
+// newIdempotentHandler returns a handler answering with a counter, so replays can be told apart
+func newIdempotentHandler(t *testing.T) http.Handler {
+	t.Helper()
+	ipResolver, err := ratelimit.NewIPResolver(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprintf(w, "response %d", calls)
+	})
+
+	deduplicator := idempotency.NewDeduplicator(cache.NewMemoryStore(10), time.Minute)
+	return Idempotency(deduplicator, testMaxBodyBytes, ipResolver, localization.NewLocalizer(), loggingtest.NewLogger())(next)
+}
+
+// testMaxBodyBytes is the body limit of the idempotent test handlers
+const testMaxBodyBytes = 1 << 10
+
+// multipartRequest builds a form with the field, each call uses a new random boundary
+func multipartRequest(t *testing.T, key, value string, header map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField(key, value)
+	form.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	r.Header.Set(IdempotencyKeyHeader, "key-1")
+	for name, value := range header {
+		r.Header.Set(name, value)
+	}
+	return r
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotencyReplaysRetries(t *testing.T) {
+	handler := newIdempotentHandler(t)
+	device := map[string]string{"X-Device-ID": "device-a"}
+
+	first := serve(handler, multipartRequest(t, "query", "bottle", device))
+	retry := serve(handler, multipartRequest(t, "query", "bottle", device))
+
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("retry got %q, want the replayed %q", retry.Body.String(), first.Body.String())
+	}
+	if retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Error("retry is not marked as replayed")
+	}
+}
+
+func TestIdempotencyRejectsReusedKey(t *testing.T) {
+	handler := newIdempotentHandler(t)
+	device := map[string]string{"X-Device-ID": "device-a"}
+
+	serve(handler, multipartRequest(t, "query", "bottle", device))
+	reused := serve(handler, multipartRequest(t, "query", "battery", device))
+
+	if reused.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key got status %d, want %d", reused.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestIdempotencyScopesAnonymousClients(t *testing.T) {
+	handler := newIdempotentHandler(t)
+
+	first := serve(handler, multipartRequest(t, "query", "bottle", map[string]string{"X-Device-ID": "device-a"}))
+	otherDevice := serve(handler, multipartRequest(t, "query", "bottle", map[string]string{"X-Device-ID": "device-b"}))
+	if otherDevice.Body.String() == first.Body.String() || otherDevice.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("another device got the stored response")
+	}
+
+	r := multipartRequest(t, "query", "bottle", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	serve(handler, r)
+	r = multipartRequest(t, "query", "bottle", nil)
+	r.RemoteAddr = "192.0.2.2:1234"
+	if otherIP := serve(handler, r); otherIP.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("another client IP got the stored response")
+	}
+}
+
+func TestIdempotencyRejectsTooLargeBodies(t *testing.T) {
+	handler := newIdempotentHandler(t)
+
+	w := serve(handler, multipartRequest(t, "query", strings.Repeat("a", testMaxBodyBytes), nil))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("too large request got status %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestIdempotencyCollapsesConcurrentDuplicates(t *testing.T) {
+	ipResolver, err := ratelimit.NewIPResolver(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const requests = 10
+	var calls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		fmt.Fprintf(w, "response %d", calls.Load())
+	})
+	deduplicator := idempotency.NewDeduplicator(cache.NewMemoryStore(10), time.Minute)
+	handler := Idempotency(deduplicator, testMaxBodyBytes, ipResolver, localization.NewLocalizer(), loggingtest.NewLogger())(next)
+
+	responses := make([]*httptest.ResponseRecorder, requests)
+	var wg sync.WaitGroup
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = serve(handler, multipartRequest(t, "query", "bottle", map[string]string{"X-Device-ID": "device-a"}))
+		}()
+	}
+
+	// Duplicates arriving after the first request completed are answered from the store, so the
+	// handler runs once however the requests interleave
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want once", calls.Load())
+	}
+	replayed := 0
+	for _, response := range responses {
+		if response.Code != http.StatusOK || response.Body.String() != "response 1" {
+			t.Errorf("duplicate got %d %q, want the response of the first request", response.Code, response.Body.String())
+		}
+		if response.Header().Get(IdempotentReplayedHeader) == "true" {
+			replayed++
+		}
+	}
+	if replayed != requests-1 {
+		t.Errorf("%d responses are marked as replayed, want %d", replayed, requests-1)
+	}
+}
+
+func TestIdempotencyOutlivesTheFirstClient(t *testing.T) {
+	ipResolver, err := ratelimit.NewIPResolver(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		if err := r.Context().Err(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "classified")
+	})
+	deduplicator := idempotency.NewDeduplicator(cache.NewMemoryStore(10), time.Minute)
+	handler := Idempotency(deduplicator, testMaxBodyBytes, ipResolver, localization.NewLocalizer(), loggingtest.NewLogger())(next)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := multipartRequest(t, "query", "bottle", map[string]string{"X-Device-ID": "device-a"}).WithContext(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serve(handler, first)
+	}()
+
+	// The first client disconnects while its request is processed and a duplicate waits for it
+	<-started
+	cancel()
+	duplicate := make(chan *httptest.ResponseRecorder)
+	go func() {
+		duplicate <- serve(handler, multipartRequest(t, "query", "bottle", map[string]string{"X-Device-ID": "device-a"}))
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	<-done
+
+	if response := <-duplicate; response.Code != http.StatusOK || response.Body.String() != "classified" {
+		t.Errorf("duplicate got %d %q, want the completed response", response.Code, response.Body.String())
+	}
+}
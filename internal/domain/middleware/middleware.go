@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+)
+
+// Middleware wraps an HTTP handler
+type Middleware func(http.Handler) http.Handler
+
+// Chain applies the middlewares to the handler, the first middleware is the outermost
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// requestLanguage returns the language of the request from the language query parameter or the Accept-Language header.
+// The form is not parsed, so middlewares can reject requests before the body is read.
+func requestLanguage(r *http.Request, localizer *localization.Localizer) string {
+	if language := r.URL.Query().Get("language"); localizer.IsLanguageSupported(language) {
+		return language
+	}
+
+	return localizer.LanguageFromHeader(r.Header.Get("Accept-Language"))
+}
+
+// writeError writes a localized JSON error response
+func writeError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(&models.WasteSortingResponse{
+		Success: false,
+		Error:   message,
+	})
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/ratelimit"
+)
+
+// RateLimit rejects requests of clients that exceeded their limit with 429 Too Many Requests.
+// If the bucket store fails, requests are let through rather than blocking all clients.
+func RateLimit(limiter *ratelimit.Limiter, localizer *localization.Localizer, logger logging.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			result, err := limiter.Allow(ctx, r)
+			if err != nil {
+				logger.Error(ctx, map[string]interface{}{
+					"message": "Rate limiter failed",
+					"error":   err.Error(),
+				})
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !result.Allowed {
+				retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+				logger.Warning(ctx, map[string]interface{}{
+					"message":     "Rate limit exceeded",
+					"client_ip":   limiter.ClientIP(r),
+					"retry_after": retryAfter,
+				})
+
+				w.Header().Set("Retry-After", strconv.Itoa(max(1, retryAfter)))
+				writeError(w, localizer.GetErrorMessage(requestLanguage(r, localizer), "rate_limited"), http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
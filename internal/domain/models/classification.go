@@ -0,0 +1,20 @@
+package models
+
+// Classification represents the language-neutral result of classifying a waste item.
+// Texts are in English, bins use their German names.
+type Classification struct {
+	Item       string                `json:"item"`
+	Category   string                `json:"category"`
+	Components []ClassifiedComponent `json:"components"`
+	Notes      []string              `json:"notes,omitempty"`
+	// PromptVersion identifies the prompt that produced the classification
+	PromptVersion string `json:"prompt_version,omitempty"`
+}
+
+// ClassifiedComponent represents a single component of the item and the bin it belongs to
+type ClassifiedComponent struct {
+	Name        string   `json:"name"`
+	Material    string   `json:"material"`
+	Bin         string   `json:"bin"`
+	Preparation []string `json:"preparation,omitempty"`
+}
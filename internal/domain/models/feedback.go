@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// FeedbackRequest represents the incoming feedback on a classification
+type FeedbackRequest struct {
+	ClassificationID string `json:"classification_id"`
+	Helpful          *bool  `json:"helpful"`
+	Component        string `json:"component,omitempty"`
+	CorrectedBin     string `json:"corrected_bin,omitempty"`
+	Comment          string `json:"comment,omitempty"`
+	Language         string `json:"language"`
+}
+
+// Feedback represents the thumbs up or down of a user on a classification
+type Feedback struct {
+	ClassificationID string    `json:"classification_id"`
+	Category         string    `json:"category"`
+	Helpful          bool      `json:"helpful"`
+	Component        string    `json:"component,omitempty"`
+	CorrectedBin     string    `json:"corrected_bin,omitempty"`
+	Comment          string    `json:"comment,omitempty"`
+	Variant          string    `json:"variant,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// CategoryAccuracy represents the aggregated feedback of an item category and experiment variant
+type CategoryAccuracy struct {
+	Category string  `json:"category"`
+	Variant  string  `json:"variant,omitempty"`
+	Total    int     `json:"total"`
+	Helpful  int     `json:"helpful"`
+	Accuracy float64 `json:"accuracy"`
+	// Corrections counts the bins users named as correct instead
+	Corrections map[string]int `json:"corrections,omitempty"`
+}
+
+// FeedbackResponse represents the API response of the feedback endpoints
+type FeedbackResponse struct {
+	Success  bool                `json:"success"`
+	Accuracy []*CategoryAccuracy `json:"accuracy,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// HistoryEntry represents a classified item in the history of an anonymous device
+type HistoryEntry struct {
+	ID             string          `json:"id"`
+	DeviceID       string          `json:"-"`
+	CreatedAt      time.Time       `json:"created_at"`
+	PostalCode     string          `json:"postal_code"`
+	Language       string          `json:"language"`
+	Query          string          `json:"query,omitempty"`
+	ImageCount     int             `json:"image_count"`
+	Classification *Classification `json:"classification"`
+	Variant        string          `json:"variant,omitempty"`
+}
+
+// HistoryResponse represents the API response of the history endpoints
+type HistoryResponse struct {
+	Success bool            `json:"success"`
+	Items   []*HistoryEntry `json:"items,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
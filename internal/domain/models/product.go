@@ -0,0 +1,15 @@
+package models
+
+// Product represents a packaged product identified by its barcode
+type Product struct {
+	EAN       string               `json:"ean"`
+	Name      string               `json:"name"`
+	Brand     string               `json:"brand,omitempty"`
+	Packaging []PackagingComponent `json:"packaging"`
+}
+
+// PackagingComponent represents a single part of a product's packaging and its material
+type PackagingComponent struct {
+	Component string `json:"component"`
+	Material  string `json:"material"`
+}
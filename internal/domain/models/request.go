@@ -4,16 +4,30 @@ import "mime/multipart"
 
 // WasteSortingRequest represents the incoming request structure
 type WasteSortingRequest struct {
-	PostalCode    string                `json:"postal_code"`
-	RecaptchaCode string                `json:"recaptcha_code"`
-	Language      string                `json:"language"`
-	ImageFile     multipart.File        `json:"-"`
-	ImageHeader   *multipart.FileHeader `json:"-"`
+	PostalCode      string        `json:"postal_code"`
+	RecaptchaCode   string        `json:"recaptcha_code"`
+	CaptchaProvider string        `json:"captcha_provider,omitempty"`
+	Language        string        `json:"language"`
+	Query           string        `json:"query,omitempty"`
+	DeviceID        string        `json:"device_id,omitempty"`
+	Images          []ImageUpload `json:"-"`
+}
+
+// ImageUpload represents a single uploaded image part
+type ImageUpload struct {
+	File   multipart.File        `json:"-"`
+	Header *multipart.FileHeader `json:"-"`
 }
 
 // WasteSortingResponse represents the API response structure
 type WasteSortingResponse struct {
 	Success bool   `json:"success"`
 	HTML    string `json:"html,omitempty"`
+	// ClassificationID references the classification in feedback
+	ClassificationID string `json:"classification_id,omitempty"`
+	// PromptVersion identifies the prompt that produced the classification
+	PromptVersion string `json:"prompt_version,omitempty"`
+	// Variant names the experiment variant the request was assigned to
+	Variant string `json:"variant,omitempty"`
 	Error   string `json:"error,omitempty"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,148 @@
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+)
+
+// templates holds the prompt templates, named <prompt>.<version>.<system|user>.tmpl
+//
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// Prompt names
+const (
+	Classification = "classification"
+	Translation    = "translation"
+)
+
+// funcs are the helper functions available in prompt templates
+var funcs = template.FuncMap{
+	"quote": func(value string) string {
+		return fmt.Sprintf("%q", value)
+	},
+	"packaging": func(components []models.PackagingComponent) string {
+		materials := make([]string, 0, len(components))
+		for _, component := range components {
+			materials = append(materials, fmt.Sprintf("%s: %s", component.Component, component.Material))
+		}
+		return strings.Join(materials, ", ")
+	},
+}
+
+// Prompt is a versioned pair of system instruction and user prompt templates
+type Prompt struct {
+	Name    string
+	Version string
+	system  *template.Template
+	user    *template.Template
+}
+
+// ID identifies the prompt version in logs, traces, caches and responses, e.g. classification@v1
+func (p *Prompt) ID() string {
+	return p.Name + "@" + p.Version
+}
+
+// System renders the system instruction
+func (p *Prompt) System(data interface{}) (string, error) {
+	return render(p.system, data)
+}
+
+// User renders the user prompt
+func (p *Prompt) User(data interface{}) (string, error) {
+	return render(p.user, data)
+}
+
+// Registry holds the prompts embedded in the binary by name and version
+type Registry struct {
+	prompts map[string]map[string]*Prompt
+}
+
+// NewRegistry parses the embedded prompt templates
+func NewRegistry() (*Registry, error) {
+	files, err := fs.Glob(templates, "templates/*.tmpl")
+	if err != nil {
+		return nil, fmt.Errorf("error listing prompt templates: %v", err)
+	}
+
+	registry := &Registry{prompts: map[string]map[string]*Prompt{}}
+	for _, file := range files {
+		parts := strings.Split(strings.TrimSuffix(path.Base(file), ".tmpl"), ".")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid prompt template name %s", file)
+		}
+		name, version, role := parts[0], parts[1], parts[2]
+
+		tmpl, err := template.New(path.Base(file)).Funcs(funcs).ParseFS(templates, file)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing prompt template %s: %v", file, err)
+		}
+
+		if registry.prompts[name] == nil {
+			registry.prompts[name] = map[string]*Prompt{}
+		}
+		prompt := registry.prompts[name][version]
+		if prompt == nil {
+			prompt = &Prompt{Name: name, Version: version}
+			registry.prompts[name][version] = prompt
+		}
+
+		switch role {
+		case "system":
+			prompt.system = tmpl
+		case "user":
+			prompt.user = tmpl
+		default:
+			return nil, fmt.Errorf("invalid prompt template role %s", file)
+		}
+	}
+
+	for name, versions := range registry.prompts {
+		for version, prompt := range versions {
+			if prompt.system == nil || prompt.user == nil {
+				return nil, fmt.Errorf("prompt %s@%s needs a system and a user template", name, version)
+			}
+		}
+	}
+
+	return registry, nil
+}
+
+// Get returns the prompt with the given name and version
+func (r *Registry) Get(name, version string) (*Prompt, error) {
+	prompt, exists := r.prompts[name][version]
+	if !exists {
+		return nil, fmt.Errorf("unknown prompt %s@%s, available versions: %s", name, version, strings.Join(r.Versions(name), ", "))
+	}
+
+	return prompt, nil
+}
+
+// Versions returns the available versions of the prompt
+func (r *Registry) Versions(name string) []string {
+	versions := make([]string, 0, len(r.prompts[name]))
+	for version := range r.prompts[name] {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	return versions
+}
+
+// render executes the template and trims surrounding whitespace
+func render(tmpl *template.Template, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error rendering prompt %s: %v", tmpl.Name(), err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
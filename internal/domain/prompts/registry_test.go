@@ -0,0 +1,63 @@
+package prompts
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// idPattern is the form of prompt IDs returned to clients and used in cache keys
+var idPattern = regexp.MustCompile(`^[a-z]+@v[0-9]+$`)
+
+func TestRegistryLoadsEveryTemplate(t *testing.T) {
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := fs.Glob(templates, "templates/*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no prompt templates are embedded")
+	}
+	for _, file := range files {
+		parts := strings.Split(path.Base(file), ".")
+		prompt, err := registry.Get(parts[0], parts[1])
+		if err != nil {
+			t.Errorf("template %s: %v", file, err)
+			continue
+		}
+		if !idPattern.MatchString(prompt.ID()) || prompt.ID() != parts[0]+"@"+parts[1] {
+			t.Errorf("template %s has prompt ID %q, want name@version", file, prompt.ID())
+		}
+	}
+}
+
+func TestRegistryHasDefaultVersions(t *testing.T) {
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{Classification, Translation} {
+		if _, err := registry.Get(name, "v1"); err != nil {
+			t.Errorf("Get(%s, v1) error = %v, want the default version", name, err)
+		}
+	}
+}
+
+func TestRegistryRejectsUnknownVersion(t *testing.T) {
+	registry, err := NewRegistry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = registry.Get(Classification, "v0")
+	if err == nil || !strings.Contains(err.Error(), "available versions:") {
+		t.Errorf("Get(classification, v0) error = %v, want the available versions", err)
+	}
+}
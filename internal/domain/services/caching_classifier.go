@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/cache"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CachingClassifier caches the results of another classifier by perceptual image hash and postal region
+type CachingClassifier struct {
+	classifier Classifier
+	store      cache.Store
+	ttl        time.Duration
+	version    string
+}
+
+// NewCachingClassifier creates a new caching classifier.
+// The version, such as the prompt ID, is part of the key, so results of other versions are not reused.
+func NewCachingClassifier(classifier Classifier, store cache.Store, ttl time.Duration, version string) *CachingClassifier {
+	return &CachingClassifier{
+		classifier: classifier,
+		store:      store,
+		ttl:        ttl,
+		version:    version,
+	}
+}
+
+// Classify returns the cached result if there is one, otherwise classifies the input and caches the result.
+// Cache failures are not fatal, the input is then classified without caching.
+func (c *CachingClassifier) Classify(ctx context.Context, input *ClassificationInput) (*models.Classification, error) {
+	span := trace.SpanFromContext(ctx)
+	key := c.cacheKey(input)
+
+	if cached, found, err := c.store.Get(ctx, key); err == nil && found {
+		var classification models.Classification
+		if err := json.Unmarshal([]byte(cached), &classification); err == nil {
+			span.SetAttributes(attribute.Bool("cache.classification.hit", true))
+			metrics.RecordCacheLookup(ctx, metrics.CacheClassification, true)
+			return &classification, nil
+		}
+	} else if err != nil {
+		span.SetAttributes(attribute.String("cache.error", err.Error()))
+	}
+	span.SetAttributes(attribute.Bool("cache.classification.hit", false))
+	metrics.RecordCacheLookup(ctx, metrics.CacheClassification, false)
+
+	classification, err := c.classifier.Classify(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(classification); err == nil {
+		if err := c.store.Set(ctx, key, string(data), c.ttl); err != nil {
+			span.SetAttributes(attribute.String("cache.error", err.Error()))
+		}
+	}
+
+	return classification, nil
+}
+
+// cacheKey builds the key from the image fingerprints or the normalized query
+// and the postal code region (Leitregion, the first two digits).
+// Products are not part of the key as they are derived from the images.
+func (c *CachingClassifier) cacheKey(input *ClassificationInput) string {
+	subject := make([]string, 0, len(input.Images))
+	for _, image := range input.Images {
+		subject = append(subject, imageFingerprint(image))
+	}
+	if input.Query != "" {
+		subject = append(subject, "q:"+strings.ToLower(strings.Join(strings.Fields(input.Query), " ")))
+	}
+
+	region := input.PostalCode
+	if len(region) > 2 {
+		region = region[:2]
+	}
+
+	return "classification:" + c.version + ":" + strings.Join(subject, ",") + ":" + region
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/cache"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/tracing/tracingtest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// countingClassifier classifies everything as a glass bottle and counts its calls
+type countingClassifier struct {
+	calls int
+}
+
+func (c *countingClassifier) Classify(context.Context, *ClassificationInput) (*models.Classification, error) {
+	c.calls++
+	return &models.Classification{Item: "glass bottle", Category: "glass", PromptVersion: "classification@v1"}, nil
+}
+
+// countingTranslator renders the item with the language and counts its calls
+type countingTranslator struct {
+	calls int
+}
+
+func (t *countingTranslator) Translate(_ context.Context, classification *models.Classification, language string) (string, error) {
+	t.calls++
+	return "<p>" + language + ": " + classification.Item + "</p>", nil
+}
+
+// grayImage returns a prepared image with a horizontal gradient, brightened by offset
+func grayImage(offset uint8) *PreparedImage {
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.SetGray(x, y, color.Gray{Y: uint8(x*12) + offset})
+		}
+	}
+	return &PreparedImage{Data: img.Pix, MIMEType: "image/jpeg", Image: img}
+}
+
+// cacheHit classifies the input in a span and returns whether the span was marked as a cache hit
+func cacheHit(t *testing.T, tracer *tracingtest.Tracer, classifier Classifier, input *ClassificationInput) bool {
+	t.Helper()
+	ctx, span := tracer.Start(context.Background(), "Classify")
+	if _, err := classifier.Classify(ctx, input); err != nil {
+		t.Fatal(err)
+	}
+	span.End()
+
+	spans := tracer.Spans()
+	for _, attr := range spans[len(spans)-1].Attributes() {
+		if attr.Key == "cache.classification.hit" {
+			return attr.Value.AsBool()
+		}
+	}
+	t.Fatal("span has no cache.classification.hit attribute")
+	return false
+}
+
+func TestCachingClassifierHitsAndMisses(t *testing.T) {
+	tracer := tracingtest.NewTracer()
+	classifier := &countingClassifier{}
+	caching := NewCachingClassifier(classifier, cache.NewMemoryStore(10), time.Hour, "classification@v1/gemini")
+
+	if cacheHit(t, tracer, caching, &ClassificationInput{Images: []*PreparedImage{grayImage(0)}, PostalCode: "10115"}) {
+		t.Error("first classification was a cache hit")
+	}
+
+	tests := []struct {
+		name    string
+		input   *ClassificationInput
+		wantHit bool
+	}{
+		{"same image", &ClassificationInput{Images: []*PreparedImage{grayImage(0)}, PostalCode: "10115"}, true},
+		{"slightly brighter image", &ClassificationInput{Images: []*PreparedImage{grayImage(2)}, PostalCode: "10115"}, true},
+		{"same region", &ClassificationInput{Images: []*PreparedImage{grayImage(0)}, PostalCode: "10999"}, true},
+		{"products", &ClassificationInput{Images: []*PreparedImage{grayImage(0)}, Products: []*models.Product{{EAN: "4006381333931"}}, PostalCode: "10115"}, true},
+		{"other region", &ClassificationInput{Images: []*PreparedImage{grayImage(0)}, PostalCode: "80331"}, false},
+		{"query", &ClassificationInput{Query: "Glass  Bottle", PostalCode: "10115"}, false},
+		{"normalized query", &ClassificationInput{Query: "glass bottle", PostalCode: "10115"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if hit := cacheHit(t, tracer, caching, tt.input); hit != tt.wantHit {
+				t.Errorf("cache hit = %v, want %v", hit, tt.wantHit)
+			}
+		})
+	}
+
+	if classifier.calls != 3 {
+		t.Errorf("classifier was called %d times, want once per miss", classifier.calls)
+	}
+
+	// Results of another prompt version are not reused
+	other := NewCachingClassifier(classifier, caching.store, time.Hour, "classification@v2/gemini")
+	if cacheHit(t, tracer, other, &ClassificationInput{Images: []*PreparedImage{grayImage(0)}, PostalCode: "10115"}) {
+		t.Error("classification of another version was a cache hit")
+	}
+}
+
+func TestCachingTranslatorIsKeyedByLanguage(t *testing.T) {
+	ctx := context.Background()
+	translator := &countingTranslator{}
+	caching := NewCachingTranslator(translator, cache.NewMemoryStore(10), time.Hour, "translation@v1/gemini")
+	classification := &models.Classification{Item: "glass bottle", Category: "glass"}
+
+	// The classification is shared by all languages, only its rendering is cached per language
+	for _, language := range []string{"de", "en", "de", "en"} {
+		html, err := caching.Translate(ctx, classification, language)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "<p>" + language + ": glass bottle</p>"; html != want {
+			t.Errorf("Translate(%s) = %q, want %q", language, html, want)
+		}
+	}
+
+	if translator.calls != 2 {
+		t.Errorf("translator was called %d times, want once per language", translator.calls)
+	}
+}
+
+func TestCachingTranslatorRecordsHits(t *testing.T) {
+	tracer := tracingtest.NewTracer()
+	caching := NewCachingTranslator(&countingTranslator{}, cache.NewMemoryStore(10), time.Hour, "translation@v1/gemini")
+	classification := &models.Classification{Item: "glass bottle", Category: "glass"}
+
+	for range 2 {
+		ctx, span := tracer.Start(context.Background(), "Translate")
+		if _, err := caching.Translate(ctx, classification, "de"); err != nil {
+			t.Fatal(err)
+		}
+		span.End()
+	}
+
+	spans := tracer.Spans()
+	for i, want := range []bool{false, true} {
+		if !hasAttribute(spans[i].Attributes(), attribute.Bool("cache.translation.hit", want)) {
+			t.Errorf("span %d has attributes %v, want cache.translation.hit=%v", i, spans[i].Attributes(), want)
+		}
+	}
+}
+
+// hasAttribute checks that the attributes contain the key with the value
+func hasAttribute(attributes []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, attr := range attributes {
+		if attr == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/cache"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CachingTranslator caches the results of another translator by classification and language
+type CachingTranslator struct {
+	translator Translator
+	store      cache.Store
+	ttl        time.Duration
+	version    string
+}
+
+// NewCachingTranslator creates a new caching translator.
+// The version, such as the prompt ID, is part of the key, so translations of other versions are not reused.
+func NewCachingTranslator(translator Translator, store cache.Store, ttl time.Duration, version string) *CachingTranslator {
+	return &CachingTranslator{
+		translator: translator,
+		store:      store,
+		ttl:        ttl,
+		version:    version,
+	}
+}
+
+// Translate returns the cached translation if there is one, otherwise translates and caches the result.
+// Cache failures are not fatal, the classification is then translated without caching.
+func (t *CachingTranslator) Translate(ctx context.Context, classification *models.Classification, language string) (string, error) {
+	span := trace.SpanFromContext(ctx)
+
+	data, err := json.Marshal(classification)
+	if err != nil {
+		return t.translator.Translate(ctx, classification, language)
+	}
+	sum := sha256.Sum256(data)
+	key := "translation:" + t.version + ":" + hex.EncodeToString(sum[:16]) + ":" + language
+
+	if cached, found, err := t.store.Get(ctx, key); err == nil && found {
+		span.SetAttributes(attribute.Bool("cache.translation.hit", true))
+		metrics.RecordCacheLookup(ctx, metrics.CacheTranslation, true)
+		return cached, nil
+	} else if err != nil {
+		span.SetAttributes(attribute.String("cache.error", err.Error()))
+	}
+	span.SetAttributes(attribute.Bool("cache.translation.hit", false))
+	metrics.RecordCacheLookup(ctx, metrics.CacheTranslation, false)
+
+	html, err := t.translator.Translate(ctx, classification, language)
+	if err != nil {
+		return "", err
+	}
+
+	if err := t.store.Set(ctx, key, html, t.ttl); err != nil {
+		span.SetAttributes(attribute.String("cache.error", err.Error()))
+	}
+
+	return html, nil
+}
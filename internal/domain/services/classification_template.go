@@ -0,0 +1,27 @@
+package services
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+)
+
+// classificationTemplate renders a classification as HTML without translation.
+// It is used when the translation call fails, so the user still gets the English instructions.
+var classificationTemplate = template.Must(template.New("classification").Parse(`<h2>{{.Item}}</h2>
+{{range .Components}}<h3>{{.Name}} ({{.Material}})</h3>
+<p><strong>{{.Bin}}</strong></p>
+{{if .Preparation}}<ul>{{range .Preparation}}<li>{{.}}</li>{{end}}</ul>
+{{end}}{{end}}{{if .Notes}}<ul>{{range .Notes}}<li>{{.}}</li>{{end}}</ul>
+{{end}}`))
+
+// renderClassificationHTML renders the classification with the built-in template
+func renderClassificationHTML(classification *models.Classification) (string, error) {
+	var buf bytes.Buffer
+	if err := classificationTemplate.Execute(&buf, classification); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
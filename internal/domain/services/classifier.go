@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+)
+
+// Classifier interface for classifying waste items into a language-neutral result
+type Classifier interface {
+	Classify(ctx context.Context, input *ClassificationInput) (*models.Classification, error)
+}
+
+// Translator interface for rendering a classification as HTML in the user's language
+type Translator interface {
+	Translate(ctx context.Context, classification *models.Classification, language string) (string, error)
+}
+
+// ClassificationInput holds everything known about the item to classify.
+// Either Images or Query is set. The language is not part of the input,
+// so the same item is classified only once for all languages.
+type ClassificationInput struct {
+	Images     []*PreparedImage
+	Query      string
+	Products   []*models.Product
+	PostalCode string
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/experiments"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/auth"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ExperimentArm holds the classifier and translator configured for an experiment variant
+type ExperimentArm struct {
+	Classifier Classifier
+	Translator Translator
+}
+
+// ExperimentRouter assigns requests to the variants of an experiment
+type ExperimentRouter struct {
+	experiment *experiments.Experiment
+	arms       map[string]*ExperimentArm
+}
+
+// NewExperimentRouter creates a new experiment router with an arm per variant name
+func NewExperimentRouter(experiment *experiments.Experiment, arms map[string]*ExperimentArm) *ExperimentRouter {
+	return &ExperimentRouter{
+		experiment: experiment,
+		arms:       arms,
+	}
+}
+
+// Route returns the variant label, such as "prompt-v2/treatment", and the arm of the request's client.
+// Clients are identified by their device ID, or by their API key if they send none.
+// Requests without either are not part of the experiment and get no arm.
+func (r *ExperimentRouter) Route(ctx context.Context, req *models.WasteSortingRequest) (string, *ExperimentArm) {
+	clientID := ""
+	if req.DeviceID != "" {
+		clientID = "device:" + req.DeviceID
+	} else if client := auth.ClientFromContext(ctx); client != nil {
+		clientID = "key:" + client.ID
+	}
+	if clientID == "" {
+		return "", nil
+	}
+
+	variant := r.experiment.Assign(clientID)
+	arm, exists := r.arms[variant.Name]
+	if !exists {
+		return "", nil
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("experiment.name", r.experiment.Name),
+		attribute.String("experiment.variant", variant.Name),
+	)
+
+	return r.experiment.Name + "/" + variant.Name, arm
+}
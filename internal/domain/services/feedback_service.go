@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging"
+)
+
+// maxCommentLength is the maximum number of characters in a feedback comment
+const maxCommentLength = 1000
+
+// FeedbackRepository interface for storing user feedback on classifications
+type FeedbackRepository interface {
+	SaveFeedback(ctx context.Context, feedback *models.Feedback) error
+	FeedbackAccuracy(ctx context.Context) ([]*models.CategoryAccuracy, error)
+}
+
+// FeedbackService records user feedback and aggregates it for prompt tuning
+type FeedbackService struct {
+	history      HistoryRepository
+	repository   FeedbackRepository
+	localization *localization.Localizer
+	now          func() time.Time
+	logger       logging.Logger
+}
+
+// NewFeedbackService creates a new feedback service, classifications are looked up in the history
+func NewFeedbackService(history HistoryRepository, repository FeedbackRepository, localizer *localization.Localizer, now func() time.Time, logger logging.Logger) *FeedbackService {
+	return &FeedbackService{
+		history:      history,
+		repository:   repository,
+		localization: localizer,
+		now:          now,
+		logger:       logger,
+	}
+}
+
+// Submit records the feedback on a classification, later feedback on the same classification replaces it
+func (s *FeedbackService) Submit(ctx context.Context, req *models.FeedbackRequest) (*models.FeedbackResponse, error) {
+	if !s.isValidFeedback(req) {
+		s.logger.Info(ctx, map[string]interface{}{
+			"message": "Rejected invalid feedback",
+			"reason":  "invalid_feedback",
+		})
+		return &models.FeedbackResponse{
+			Success: false,
+			Error:   s.localization.GetErrorMessage(req.Language, "invalid_feedback"),
+		}, nil
+	}
+
+	entry, err := s.history.Get(ctx, req.ClassificationID)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		s.logger.Info(ctx, map[string]interface{}{
+			"message":           "Rejected feedback on unknown classification",
+			"reason":            "classification_not_found",
+			"classification_id": req.ClassificationID,
+		})
+		return &models.FeedbackResponse{
+			Success: false,
+			Error:   s.localization.GetErrorMessage(req.Language, "classification_not_found"),
+		}, nil
+	}
+
+	err = s.repository.SaveFeedback(ctx, &models.Feedback{
+		ClassificationID: entry.ID,
+		Category:         entry.Classification.Category,
+		Helpful:          *req.Helpful,
+		Component:        strings.TrimSpace(req.Component),
+		CorrectedBin:     req.CorrectedBin,
+		Comment:          strings.TrimSpace(req.Comment),
+		Variant:          entry.Variant,
+		CreatedAt:        s.now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info(ctx, map[string]interface{}{
+		"message":           "Recorded feedback",
+		"classification_id": entry.ID,
+		"category":          entry.Classification.Category,
+		"helpful":           *req.Helpful,
+		"corrected_bin":     req.CorrectedBin,
+		"variant":           entry.Variant,
+	})
+
+	return &models.FeedbackResponse{Success: true}, nil
+}
+
+// Accuracy returns the share of helpful classifications and the corrected bins per item category and experiment variant
+func (s *FeedbackService) Accuracy(ctx context.Context) (*models.FeedbackResponse, error) {
+	accuracy, err := s.repository.FeedbackAccuracy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Debug(ctx, map[string]interface{}{
+		"message":        "Aggregated feedback",
+		"category_count": len(accuracy),
+	})
+
+	return &models.FeedbackResponse{
+		Success:  true,
+		Accuracy: accuracy,
+	}, nil
+}
+
+// isValidFeedback checks that the feedback references a classification, has a rating,
+// names a known bin if it corrects one and has a comment of reasonable length
+func (s *FeedbackService) isValidFeedback(req *models.FeedbackRequest) bool {
+	if req.ClassificationID == "" || req.Helpful == nil {
+		return false
+	}
+	if req.CorrectedBin != "" && !slices.Contains(wasteBins, req.CorrectedBin) {
+		return false
+	}
+	return utf8.RuneCountInString(req.Comment) <= maxCommentLength && utf8.RuneCountInString(req.Component) <= maxQueryLength
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging/loggingtest"
+)
+
+// memoryHistory stores history entries and feedback in memory
+type memoryHistory struct {
+	entries  map[string]*models.HistoryEntry
+	feedback []*models.Feedback
+}
+
+func newMemoryHistory(entries ...*models.HistoryEntry) *memoryHistory {
+	history := &memoryHistory{entries: map[string]*models.HistoryEntry{}}
+	for _, entry := range entries {
+		history.entries[entry.ID] = entry
+	}
+	return history
+}
+
+func (h *memoryHistory) Save(_ context.Context, entry *models.HistoryEntry) error {
+	h.entries[entry.ID] = entry
+	return nil
+}
+
+func (h *memoryHistory) Get(_ context.Context, id string) (*models.HistoryEntry, error) {
+	return h.entries[id], nil
+}
+
+func (h *memoryHistory) List(_ context.Context, deviceID string, limit int) ([]*models.HistoryEntry, error) {
+	var entries []*models.HistoryEntry
+	for _, entry := range h.entries {
+		if entry.DeviceID == deviceID && len(entries) < limit {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (h *memoryHistory) Delete(_ context.Context, deviceID string) error {
+	for id, entry := range h.entries {
+		if entry.DeviceID == deviceID {
+			delete(h.entries, id)
+		}
+	}
+	return nil
+}
+
+func (h *memoryHistory) SaveFeedback(_ context.Context, feedback *models.Feedback) error {
+	h.feedback = append(h.feedback, feedback)
+	return nil
+}
+
+func (h *memoryHistory) FeedbackAccuracy(context.Context) ([]*models.CategoryAccuracy, error) {
+	return nil, nil
+}
+
+// testHistoryEntry is a classification of the device device-1234
+var testHistoryEntry = &models.HistoryEntry{
+	ID:             "entry-1",
+	DeviceID:       "device-1234",
+	Classification: &models.Classification{Item: "pizza box", Category: "packaging"},
+	Variant:        "prompt-v2/treatment",
+}
+
+func TestFeedbackServiceSubmit(t *testing.T) {
+	helpful := false
+	tests := []struct {
+		name        string
+		req         *models.FeedbackRequest
+		wantSuccess bool
+		wantMessage string
+	}{
+		{"recorded", &models.FeedbackRequest{ClassificationID: "entry-1", Helpful: &helpful, CorrectedBin: "Restmüll"}, true, "Recorded feedback"},
+		{"without rating", &models.FeedbackRequest{ClassificationID: "entry-1"}, false, "Rejected invalid feedback"},
+		{"unknown bin", &models.FeedbackRequest{ClassificationID: "entry-1", Helpful: &helpful, CorrectedBin: "Sondermüll"}, false, "Rejected invalid feedback"},
+		{"unknown classification", &models.FeedbackRequest{ClassificationID: "entry-2", Helpful: &helpful}, false, "Rejected feedback on unknown classification"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := newMemoryHistory(testHistoryEntry)
+			logger := loggingtest.NewLogger()
+			service := NewFeedbackService(history, history, localization.NewLocalizer(), time.Now, logger)
+
+			response, err := service.Submit(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Submit() error = %v", err)
+			}
+			if response.Success != tt.wantSuccess {
+				t.Errorf("Submit() success = %v, want %v", response.Success, tt.wantSuccess)
+			}
+			if tt.wantSuccess != (len(history.feedback) == 1) {
+				t.Errorf("stored %d feedback entries", len(history.feedback))
+			}
+			if !slices.Contains(logger.Messages(), tt.wantMessage) {
+				t.Errorf("logged %q, want %q", logger.Messages(), tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestFeedbackServiceSubmitKeepsVariant(t *testing.T) {
+	history := newMemoryHistory(testHistoryEntry)
+	service := NewFeedbackService(history, history, localization.NewLocalizer(), time.Now, loggingtest.NewLogger())
+
+	helpful := true
+	if _, err := service.Submit(context.Background(), &models.FeedbackRequest{ClassificationID: "entry-1", Helpful: &helpful}); err != nil {
+		t.Fatal(err)
+	}
+	if len(history.feedback) != 1 || history.feedback[0].Category != "packaging" || history.feedback[0].Variant != "prompt-v2/treatment" {
+		t.Errorf("stored feedback %+v, want the category and variant of the classification", history.feedback)
+	}
+}
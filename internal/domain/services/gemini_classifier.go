@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/prompts"
+	"google.golang.org/genai"
+)
+
+// wasteBins lists the German bins and collection points a component can be sorted into
+var wasteBins = []string{
+	"Restmüll",
+	"Gelbe Tonne/Gelber Sack",
+	"Papiertonne",
+	"Biotonne",
+	"Glascontainer",
+	"Pfandrückgabe",
+	"Elektroschrott-Sammlung",
+	"Batteriesammlung",
+	"Schadstoffsammlung",
+	"Wertstoffhof",
+	"Altkleidercontainer",
+}
+
+// classificationSchema is the response schema of the structured classification
+var classificationSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"item":     {Type: genai.TypeString, Description: "Short English name of the item"},
+		"category": {Type: genai.TypeString, Description: "Short lowercase English item category, e.g. beverage bottle, coffee capsule"},
+		"components": {
+			Type: genai.TypeArray,
+			Items: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"name":        {Type: genai.TypeString, Description: "English name of the component"},
+					"material":    {Type: genai.TypeString, Description: "English name of the material"},
+					"bin":         {Type: genai.TypeString, Enum: wasteBins},
+					"preparation": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}, Description: "English preparation steps"},
+				},
+				Required: []string{"name", "material", "bin"},
+			},
+		},
+		"notes": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}, Description: "English notes on local regulations"},
+	},
+	Required: []string{"item", "category", "components"},
+}
+
+// GeminiClassifier classifies waste items with Gemini AI
+type GeminiClassifier struct {
+	aiClient *genai.Client
+	model    string
+	prompt   *prompts.Prompt
+}
+
+// NewGeminiClassifier creates a new Gemini classifier using the classification prompt
+func NewGeminiClassifier(aiClient *genai.Client, model string, prompt *prompts.Prompt) *GeminiClassifier {
+	return &GeminiClassifier{
+		aiClient: aiClient,
+		model:    model,
+		prompt:   prompt,
+	}
+}
+
+// Classify sends the prompt and all images in a single content, as they show the components of one item
+func (c *GeminiClassifier) Classify(ctx context.Context, input *ClassificationInput) (*models.Classification, error) {
+	data := promptData{
+		ImageCount: len(input.Images),
+		Query:      input.Query,
+		Products:   input.Products,
+		PostalCode: input.PostalCode,
+	}
+	systemInstruction, err := c.prompt.System(data)
+	if err != nil {
+		return nil, err
+	}
+	prompt, err := c.prompt.User(data)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]*genai.Part, 0, len(input.Images)+1)
+	parts = append(parts, &genai.Part{Text: prompt})
+	for _, image := range input.Images {
+		parts = append(parts, &genai.Part{InlineData: &genai.Blob{
+			Data:     image.Data,
+			MIMEType: image.MIMEType,
+		}})
+	}
+
+	contents := []*genai.Content{{
+		Parts: parts,
+		Role:  genai.RoleUser,
+	}}
+
+	resp, err := c.aiClient.Models.GenerateContent(ctx, c.model, contents, &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{{Text: systemInstruction}},
+		},
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   classificationSchema,
+	})
+	recordGenerateContent(ctx, c.model, resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate content: %v", err)
+	}
+
+	if len(resp.Candidates) == 0 {
+		return nil, fmt.Errorf("no response from Gemini")
+	}
+
+	var classification models.Classification
+	if err := json.Unmarshal([]byte(resp.Text()), &classification); err != nil {
+		return nil, fmt.Errorf("failed to parse classification: %v", err)
+	}
+	if len(classification.Components) == 0 {
+		return nil, fmt.Errorf("no components in classification")
+	}
+	classification.PromptVersion = c.prompt.ID()
+
+	return &classification, nil
+}
+
+// promptData holds the values the classification prompt templates are rendered with
+type promptData struct {
+	ImageCount int
+	Query      string
+	Products   []*models.Product
+	PostalCode string
+}
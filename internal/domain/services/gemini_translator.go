@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/prompts"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genai"
+)
+
+// htmlBodyPattern extracts the content of the HTML body from the model answer
+var htmlBodyPattern = regexp.MustCompile(`(?s)<body[^>]*>(.*?)</body>`)
+
+// GeminiTranslator renders classifications as localized HTML with a text-only Gemini call
+type GeminiTranslator struct {
+	aiClient *genai.Client
+	model    string
+	prompt   *prompts.Prompt
+}
+
+// translationData holds the values the translation prompt templates are rendered with
+type translationData struct {
+	Language       string
+	Classification string
+}
+
+// NewGeminiTranslator creates a new Gemini translator using the translation prompt, model should be a cheap text model
+func NewGeminiTranslator(aiClient *genai.Client, model string, prompt *prompts.Prompt) *GeminiTranslator {
+	return &GeminiTranslator{
+		aiClient: aiClient,
+		model:    model,
+		prompt:   prompt,
+	}
+}
+
+// Translate translates the classification into the language and formats it as HTML
+func (t *GeminiTranslator) Translate(ctx context.Context, classification *models.Classification, language string) (string, error) {
+	data, err := json.Marshal(classification)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode classification: %v", err)
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("prompt.translation", t.prompt.ID()))
+
+	promptValues := translationData{Language: language, Classification: string(data)}
+	systemInstruction, err := t.prompt.System(promptValues)
+	if err != nil {
+		return "", err
+	}
+	prompt, err := t.prompt.User(promptValues)
+	if err != nil {
+		return "", err
+	}
+
+	contents := []*genai.Content{{
+		Parts: []*genai.Part{{Text: prompt}},
+		Role:  genai.RoleUser,
+	}}
+
+	resp, err := t.aiClient.Models.GenerateContent(ctx, t.model, contents, &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{{Text: systemInstruction}},
+		},
+	})
+	recordGenerateContent(ctx, t.model, resp)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate content: %v", err)
+	}
+
+	if len(resp.Candidates) == 0 {
+		return "", fmt.Errorf("no response from Gemini")
+	}
+
+	for _, candidate := range resp.Candidates {
+		if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
+			continue
+		}
+
+		var textResponse string
+		for _, part := range candidate.Content.Parts {
+			if part.Text != "" {
+				textResponse += part.Text
+			}
+		}
+
+		if textResponse == "" {
+			continue
+		}
+
+		match := htmlBodyPattern.FindStringSubmatch(textResponse)
+		if len(match) > 1 {
+			textResponse = match[1]
+		} else {
+			textResponse = ""
+		}
+
+		textResponse = strings.TrimSpace(textResponse)
+		if textResponse == "" {
+			continue
+		}
+
+		return textResponse, nil
+	}
+
+	return "", fmt.Errorf("no valid text response from Gemini")
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging"
+)
+
+// deviceIDPattern matches the anonymous device IDs generated by the apps, such as UUIDs
+var deviceIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8,128}$`)
+
+// HistoryRepository interface for storing the classification history of anonymous devices.
+// Entries are only fetched by ID or by device ID, so a document store such as Firestore can keep
+// them as documents named by their ID with an indexed device ID field. Delete also removes the
+// feedback on the deleted entries, as its comments are written by the same device.
+type HistoryRepository interface {
+	Save(ctx context.Context, entry *models.HistoryEntry) error
+	Get(ctx context.Context, id string) (*models.HistoryEntry, error)
+	List(ctx context.Context, deviceID string, limit int) ([]*models.HistoryEntry, error)
+	Delete(ctx context.Context, deviceID string) error
+}
+
+// HistoryService handles retrieval and deletion of the classification history
+type HistoryService struct {
+	repository   HistoryRepository
+	localization *localization.Localizer
+	limit        int
+	logger       logging.Logger
+}
+
+// NewHistoryService creates a new history service returning at most limit entries
+func NewHistoryService(repository HistoryRepository, localizer *localization.Localizer, limit int, logger logging.Logger) *HistoryService {
+	return &HistoryService{
+		repository:   repository,
+		localization: localizer,
+		limit:        limit,
+		logger:       logger,
+	}
+}
+
+// List returns the most recent items of the device
+func (s *HistoryService) List(ctx context.Context, deviceID, language string) (*models.HistoryResponse, error) {
+	if !IsValidDeviceID(deviceID) {
+		s.logger.Info(ctx, map[string]interface{}{
+			"message": "Rejected history request with invalid device ID",
+			"reason":  "invalid_device_id",
+		})
+		return &models.HistoryResponse{
+			Success: false,
+			Error:   s.localization.GetErrorMessage(language, "invalid_device_id"),
+		}, nil
+	}
+
+	entries, err := s.repository.List(ctx, deviceID, s.limit)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Debug(ctx, map[string]interface{}{
+		"message":     "Listed history",
+		"entry_count": len(entries),
+	})
+
+	return &models.HistoryResponse{
+		Success: true,
+		Items:   entries,
+	}, nil
+}
+
+// Delete removes all items of the device
+func (s *HistoryService) Delete(ctx context.Context, deviceID, language string) (*models.HistoryResponse, error) {
+	if !IsValidDeviceID(deviceID) {
+		s.logger.Info(ctx, map[string]interface{}{
+			"message": "Rejected history request with invalid device ID",
+			"reason":  "invalid_device_id",
+		})
+		return &models.HistoryResponse{
+			Success: false,
+			Error:   s.localization.GetErrorMessage(language, "invalid_device_id"),
+		}, nil
+	}
+
+	if err := s.repository.Delete(ctx, deviceID); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info(ctx, map[string]interface{}{
+		"message": "Deleted history of device",
+	})
+
+	return &models.HistoryResponse{Success: true}, nil
+}
+
+// IsValidDeviceID checks the format of an anonymous device ID
+func IsValidDeviceID(deviceID string) bool {
+	return deviceIDPattern.MatchString(deviceID)
+}
+
+// newHistoryID generates a random ID for a history entry
+func newHistoryID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
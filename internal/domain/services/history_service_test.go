@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging/loggingtest"
+)
+
+func TestHistoryServiceRejectsInvalidDeviceID(t *testing.T) {
+	history := newMemoryHistory(testHistoryEntry)
+	logger := loggingtest.NewLogger()
+	service := NewHistoryService(history, localization.NewLocalizer(), 10, logger)
+
+	response, err := service.Delete(context.Background(), "bad id", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Success || len(history.entries) != 1 {
+		t.Error("history of an invalid device ID was deleted")
+	}
+	if !slices.Contains(logger.Messages(), "Rejected history request with invalid device ID") {
+		t.Errorf("logged %q, want the rejection", logger.Messages())
+	}
+}
+
+func TestHistoryServiceDelete(t *testing.T) {
+	history := newMemoryHistory(testHistoryEntry)
+	logger := loggingtest.NewLogger()
+	service := NewHistoryService(history, localization.NewLocalizer(), 10, logger)
+
+	response, err := service.Delete(context.Background(), "device-1234", "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.Success || len(history.entries) != 0 {
+		t.Error("history of the device was not deleted")
+	}
+	if !slices.Contains(logger.Messages(), "Deleted history of device") {
+		t.Errorf("logged %q, want the deletion", logger.Messages())
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	"github.com/gen2brain/heic"
+	_ "golang.org/x/image/webp"
+)
+
+// modelImageMIMETypes lists the image MIME types Gemini accepts as inline data
+var modelImageMIMETypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/heic": true,
+	"image/heif": true,
+	"image/avif": true,
+}
+
+// transcodeJPEGQuality is the JPEG quality used when converting unsupported formats
+const transcodeJPEGQuality = 90
+
+// detectImageMIMEType sniffs the MIME type of the image data.
+// ISO BMFF based formats (HEIC, HEIF, AVIF) are not recognized by http.DetectContentType,
+// so their ftyp brands are checked first.
+func detectImageMIMEType(data []byte) string {
+	if mimeType := detectISOBMFFImageType(data); mimeType != "" {
+		return mimeType
+	}
+
+	return http.DetectContentType(data)
+}
+
+// detectISOBMFFImageType inspects the ftyp box of HEIC, HEIF and AVIF files
+func detectISOBMFFImageType(data []byte) string {
+	if len(data) < 16 || string(data[4:8]) != "ftyp" {
+		return ""
+	}
+
+	boxSize := int(data[0])<<24 | int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if boxSize < 16 || boxSize > len(data) {
+		boxSize = len(data)
+	}
+
+	// Major brand followed by the minor version and the compatible brands
+	brands := []string{string(data[8:12])}
+	for offset := 16; offset+4 <= boxSize; offset += 4 {
+		brands = append(brands, string(data[offset:offset+4]))
+	}
+
+	mimeType := ""
+	for _, brand := range brands {
+		switch brand {
+		case "avif", "avis":
+			return "image/avif"
+		case "heic", "heix", "heim", "heis", "hevc", "hevx":
+			mimeType = "image/heic"
+		case "mif1", "msf1":
+			if mimeType == "" {
+				mimeType = "image/heif"
+			}
+		}
+	}
+
+	return mimeType
+}
+
+// prepareImageForModel returns the image data and MIME type to send to the model.
+// Formats supported by the model are passed through, everything else is transcoded to JPEG.
+func prepareImageForModel(data []byte) ([]byte, string, error) {
+	mimeType := detectImageMIMEType(data)
+	if modelImageMIMETypes[mimeType] {
+		return data, mimeType, nil
+	}
+
+	jpegData, err := transcodeToJPEG(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to transcode %s image: %v", mimeType, err)
+	}
+
+	return jpegData, "image/jpeg", nil
+}
+
+// decodeImage decodes the image data of the given MIME type.
+// HEIF files with a generic mif1 brand are not matched by the registered HEIC magic,
+// so HEIC and HEIF are always decoded explicitly.
+// There is no AVIF decoder, AVIF images are passed through to the model once their metadata is scrubbed.
+func decodeImage(data []byte, mimeType string) (image.Image, error) {
+	switch mimeType {
+	case "image/heic", "image/heif":
+		return heic.Decode(bytes.NewReader(data))
+	case "image/avif":
+		return nil, errors.New("no AVIF decoder")
+	default:
+		img, _, err := image.Decode(bytes.NewReader(data))
+		return img, err
+	}
+}
+
+// transcodeToJPEG decodes the image with the registered decoders and encodes it as JPEG
+func transcodeToJPEG(data []byte) ([]byte, error) {
+	img, err := decodeImage(data, detectImageMIMEType(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: transcodeJPEGQuality}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// avifHeader is the ftyp box of an AVIF file, enough to detect the format
+var avifHeader = []byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf\x00\x00\x00\x00meta")
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDetectImageMIMEType(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"jpeg", readTestdata(t, "sample.jpg"), "image/jpeg"},
+		{"png", readTestdata(t, "sample.png"), "image/png"},
+		{"gif", readTestdata(t, "sample.gif"), "image/gif"},
+		{"webp", readTestdata(t, "sample.webp"), "image/webp"},
+		{"heic", readTestdata(t, "sample.heic"), "image/heic"},
+		{"heif", []byte("\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00mif1miaf"), "image/heif"},
+		{"avif", avifHeader, "image/avif"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectImageMIMEType(tt.data); got != tt.want {
+				t.Errorf("detectImageMIMEType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareImageForModel(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		wantMIME    string
+		passthrough bool
+	}{
+		{"jpeg is passed through", "sample.jpg", "image/jpeg", true},
+		{"webp is passed through", "sample.webp", "image/webp", true},
+		{"heic is passed through", "sample.heic", "image/heic", true},
+		{"gif is transcoded", "sample.gif", "image/jpeg", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := readTestdata(t, tt.file)
+			got, mimeType, err := prepareImageForModel(data)
+			if err != nil {
+				t.Fatalf("prepareImageForModel() error = %v", err)
+			}
+			if mimeType != tt.wantMIME {
+				t.Errorf("prepareImageForModel() MIME type = %q, want %q", mimeType, tt.wantMIME)
+			}
+			if tt.passthrough != bytes.Equal(got, data) {
+				t.Errorf("prepareImageForModel() passed through = %v, want %v", !tt.passthrough, tt.passthrough)
+			}
+			if !tt.passthrough {
+				if _, err := jpeg.Decode(bytes.NewReader(got)); err != nil {
+					t.Errorf("transcoded image is not a JPEG: %v", err)
+				}
+			}
+		})
+	}
+
+	got, mimeType, err := prepareImageForModel(avifHeader)
+	if err != nil || mimeType != "image/avif" || !bytes.Equal(got, avifHeader) {
+		t.Errorf("prepareImageForModel() of an AVIF image = %q, %v, want it passed through", mimeType, err)
+	}
+}
+
+func TestImagePreprocessorDecodesHEIC(t *testing.T) {
+	prepared, err := NewImagePreprocessor(32, 85).ProcessUpload(context.Background(), readTestdata(t, "sample.heic"))
+	if err != nil {
+		t.Fatalf("ProcessUpload() error = %v", err)
+	}
+	if prepared.MIMEType != "image/jpeg" || prepared.Image == nil {
+		t.Fatalf("ProcessUpload() = %q with image %v, want a decoded JPEG", prepared.MIMEType, prepared.Image != nil)
+	}
+	if bounds := prepared.Image.Bounds(); max(bounds.Dx(), bounds.Dy()) != 32 {
+		t.Errorf("processed image is %dx%d, want the longest edge to be 32", bounds.Dx(), bounds.Dy())
+	}
+}
+
+func TestIsValidImageFile(t *testing.T) {
+	service := &WasteSortingService{}
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"image/jpeg", true},
+		{"image/png", true},
+		{"image/webp", true},
+		{"image/heic", true},
+		{"image/heif", true},
+		{"image/avif", true},
+		{"application/pdf", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			header := &multipart.FileHeader{Header: textproto.MIMEHeader{"Content-Type": {tt.contentType}}}
+			if got := service.isValidImageFile(header); got != tt.want {
+				t.Errorf("isValidImageFile(%q) = %v, want %v", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
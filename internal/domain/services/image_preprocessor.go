@@ -0,0 +1,228 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/image/draw"
+)
+
+// ImagePreprocessor normalizes uploaded images before they are sent to the model
+type ImagePreprocessor struct {
+	maxEdge int
+	quality int
+}
+
+// PreparedImage is an image ready to be sent to the model
+type PreparedImage struct {
+	Data     []byte
+	MIMEType string
+	// Image is the decoded image, nil if the image could not be decoded and was passed through
+	Image image.Image
+}
+
+// NewImagePreprocessor creates a new image preprocessor
+func NewImagePreprocessor(maxEdge, quality int) *ImagePreprocessor {
+	return &ImagePreprocessor{
+		maxEdge: maxEdge,
+		quality: quality,
+	}
+}
+
+// ProcessUpload removes the metadata of an uploaded image and processes it in its EXIF orientation
+func (p *ImagePreprocessor) ProcessUpload(ctx context.Context, data []byte) (*PreparedImage, error) {
+	// Remove EXIF, XMP and IPTC metadata (GPS location, camera owner) before anything else touches the image
+	data, orientation, err := scrubImageMetadata(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrub image metadata: %v", err)
+	}
+
+	return p.Process(ctx, data, orientation)
+}
+
+// Process applies the given EXIF orientation, downsizes the image to the configured max edge
+// and re-encodes it as JPEG, which also drops all metadata.
+// Images that cannot be decoded are passed through or transcoded as the model requires
+// if their metadata has been scrubbed, otherwise they are rejected.
+func (p *ImagePreprocessor) Process(ctx context.Context, data []byte, orientation int) (*PreparedImage, error) {
+	span := trace.SpanFromContext(ctx)
+	mimeType := detectImageMIMEType(data)
+	span.SetAttributes(
+		attribute.Int("image.original_bytes", len(data)),
+		attribute.String("image.original_mime_type", mimeType),
+	)
+
+	img, err := decodeImage(data, mimeType)
+	if err != nil {
+		// Passing the image through would forward the metadata of formats the scrubber does not handle
+		if !scrubbedMIMETypes[mimeType] {
+			return nil, fmt.Errorf("failed to decode %s image: %v", mimeType, err)
+		}
+		processed, processedMIMEType, err := prepareImageForModel(data)
+		if err != nil {
+			return nil, err
+		}
+		span.SetAttributes(
+			attribute.Int("image.processed_bytes", len(processed)),
+			attribute.String("image.processed_mime_type", processedMIMEType),
+		)
+		return &PreparedImage{Data: processed, MIMEType: processedMIMEType}, nil
+	}
+
+	bounds := img.Bounds()
+	rgba := p.resize(img)
+	rgba = applyOrientation(rgba, orientation)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, rgba, &jpeg.Options{Quality: p.quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %v", err)
+	}
+
+	span.SetAttributes(
+		attribute.Int("image.original_width", bounds.Dx()),
+		attribute.Int("image.original_height", bounds.Dy()),
+		attribute.Int("image.processed_width", rgba.Bounds().Dx()),
+		attribute.Int("image.processed_height", rgba.Bounds().Dy()),
+		attribute.Int("image.processed_bytes", buf.Len()),
+		attribute.String("image.processed_mime_type", "image/jpeg"),
+	)
+
+	return &PreparedImage{Data: buf.Bytes(), MIMEType: "image/jpeg", Image: rgba}, nil
+}
+
+// resize scales the image so that its longest edge fits the max edge.
+// Transparent areas are flattened onto white, as JPEG has no alpha channel.
+func (p *ImagePreprocessor) resize(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if longest := max(width, height); p.maxEdge > 0 && longest > p.maxEdge {
+		width = max(1, width*p.maxEdge/longest)
+		height = max(1, height*p.maxEdge/longest)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+
+	return dst
+}
+
+// applyOrientation transforms the image according to the EXIF orientation value (1-8)
+func applyOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		// Orientations 5-8 swap the axes
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirror horizontal
+				dx, dy = width-1-x, y
+			case 3: // rotate 180
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirror vertical
+				dx, dy = x, height-1-y
+			case 5: // mirror horizontal and rotate 270 CW
+				dx, dy = y, x
+			case 6: // rotate 90 CW
+				dx, dy = height-1-y, x
+			case 7: // mirror horizontal and rotate 90 CW
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotate 270 CW
+				dx, dy = y, width-1-x
+			}
+
+			si := src.PixOffset(x, y)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+
+	return dst
+}
+
+// readJPEGOrientation returns the EXIF orientation of a JPEG image, or 1 if it has none
+func readJPEGOrientation(data []byte) int {
+	exif := findJPEGExif(data)
+	if exif == nil {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(exif[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifdOffset := int(order.Uint32(exif[4:8]))
+	if ifdOffset+2 > len(exif) {
+		return 1
+	}
+
+	entries := int(order.Uint16(exif[ifdOffset:]))
+	for i := 0; i < entries; i++ {
+		entry := ifdOffset + 2 + i*12
+		if entry+12 > len(exif) {
+			break
+		}
+		if order.Uint16(exif[entry:]) == 0x0112 {
+			return int(order.Uint16(exif[entry+8:]))
+		}
+	}
+
+	return 1
+}
+
+// findJPEGExif returns the TIFF structure of the first EXIF APP1 segment
+func findJPEGExif(data []byte) []byte {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil
+	}
+
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xFF {
+			return nil
+		}
+		marker := data[offset+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// Start of scan or end of image, no metadata follows
+			return nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		end := offset + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+
+		segment := data[offset+4 : end]
+		if marker == 0xE1 && len(segment) >= 14 && string(segment[:6]) == "Exif\x00\x00" {
+			return segment[6:]
+		}
+
+		offset = end
+	}
+
+	return nil
+}
@@ -0,0 +1,453 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// pngSignature is the fixed 8-byte header of every PNG file
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadataChunks lists PNG chunks that may carry personal data
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// scrubbedMIMETypes lists the formats scrubImageMetadata removes the metadata from
+var scrubbedMIMETypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+	"image/avif": true,
+}
+
+// scrubImageMetadata removes EXIF (including GPS), XMP and IPTC metadata from JPEG, PNG, WebP and AVIF images.
+// The EXIF orientation is returned separately, as it is still needed to display the image upright.
+// Other formats, such as HEIC, are returned unchanged and lose their metadata when they are re-encoded.
+func scrubImageMetadata(data []byte) ([]byte, int, error) {
+	switch detectImageMIMEType(data) {
+	case "image/jpeg":
+		orientation := readJPEGOrientation(data)
+		scrubbed, err := scrubJPEGMetadata(data)
+		return scrubbed, orientation, err
+	case "image/png":
+		scrubbed, err := scrubPNGMetadata(data)
+		return scrubbed, 1, err
+	case "image/webp":
+		scrubbed, err := scrubWebPMetadata(data)
+		return scrubbed, 1, err
+	case "image/avif":
+		// AVIF is passed through to the model, its orientation is kept in the irot and imir properties
+		scrubbed, err := scrubISOBMFFMetadata(data)
+		return scrubbed, 1, err
+	default:
+		return data, 1, nil
+	}
+}
+
+// scrubJPEGMetadata drops APP1 (EXIF, XMP), APP13 (IPTC) and comment segments.
+// Segments needed for decoding, such as JFIF, ICC profiles and Adobe color transforms, are kept.
+func scrubJPEGMetadata(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, fmt.Errorf("invalid JPEG header")
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	offset := 2
+	for offset+4 <= len(data) {
+		if data[offset] != 0xFF {
+			return nil, fmt.Errorf("invalid JPEG marker at offset %d", offset)
+		}
+		marker := data[offset+1]
+		if marker == 0xDA || marker == 0xD9 {
+			// Entropy-coded data follows, there are no more metadata segments
+			break
+		}
+
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		end := offset + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, fmt.Errorf("invalid JPEG segment length at offset %d", offset)
+		}
+
+		switch marker {
+		case 0xE1, 0xED, 0xFE:
+			// EXIF/XMP, IPTC and comments
+		default:
+			out.Write(data[offset:end])
+		}
+
+		offset = end
+	}
+	out.Write(data[offset:])
+
+	return out.Bytes(), nil
+}
+
+// scrubPNGMetadata drops EXIF, textual (which holds XMP and IPTC) and timestamp chunks
+func scrubPNGMetadata(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, fmt.Errorf("invalid PNG signature")
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+
+	for offset := len(pngSignature); offset < len(data); {
+		if offset+8 > len(data) {
+			return nil, fmt.Errorf("truncated PNG chunk at offset %d", offset)
+		}
+
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		chunkType := string(data[offset+4 : offset+8])
+		// Length, type, data and CRC
+		end := offset + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, fmt.Errorf("invalid PNG chunk length at offset %d", offset)
+		}
+
+		if !pngMetadataChunks[chunkType] {
+			out.Write(data[offset:end])
+		}
+
+		offset = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+// scrubWebPMetadata drops the EXIF and XMP chunks and clears their flags in the VP8X header
+func scrubWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("invalid WebP header")
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+
+	for offset := 12; offset < len(data); {
+		if offset+8 > len(data) {
+			return nil, fmt.Errorf("truncated WebP chunk at offset %d", offset)
+		}
+
+		fourCC := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		// Chunks are padded to an even size
+		end := offset + 8 + size + size%2
+		if size < 0 || end > len(data) {
+			if offset+8+size != len(data) {
+				return nil, fmt.Errorf("invalid WebP chunk size at offset %d", offset)
+			}
+			end = len(data)
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := bytes.Clone(data[offset:end])
+			if len(chunk) > 8 {
+				// Clear the EXIF (0x08) and XMP (0x04) presence flags
+				chunk[8] &^= 0x08 | 0x04
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[offset:end])
+		}
+
+		offset = end
+	}
+
+	scrubbed := out.Bytes()
+	binary.LittleEndian.PutUint32(scrubbed[4:8], uint32(len(scrubbed)-8))
+
+	return scrubbed, nil
+}
+
+// isobmffBox is a box of an ISO base media file (HEIF, AVIF)
+type isobmffBox struct {
+	boxType string
+	// start and end are the offsets of the box payload
+	start, end int
+}
+
+// readISOBMFFBoxes splits the data between start and end into boxes
+func readISOBMFFBoxes(data []byte, start, end int) ([]isobmffBox, error) {
+	var boxes []isobmffBox
+	for offset := start; offset < end; {
+		if offset+8 > end {
+			return nil, fmt.Errorf("truncated box at offset %d", offset)
+		}
+
+		size := uint64(binary.BigEndian.Uint32(data[offset:]))
+		boxType := string(data[offset+4 : offset+8])
+		header := 8
+		switch size {
+		case 0:
+			// The box extends to the end of its parent
+			size = uint64(end - offset)
+		case 1:
+			if offset+16 > end {
+				return nil, fmt.Errorf("truncated box at offset %d", offset)
+			}
+			size = binary.BigEndian.Uint64(data[offset+8:])
+			header = 16
+		}
+		if size < uint64(header) || size > uint64(end-offset) {
+			return nil, fmt.Errorf("invalid %q box size at offset %d", boxType, offset)
+		}
+
+		boxes = append(boxes, isobmffBox{boxType: boxType, start: offset + header, end: offset + int(size)})
+		offset += int(size)
+	}
+
+	return boxes, nil
+}
+
+// scrubISOBMFFMetadata overwrites the data of the EXIF and XMP items of a HEIF or AVIF image with zeros.
+// The items are kept, so the offsets of all other items stay valid.
+func scrubISOBMFFMetadata(data []byte) ([]byte, error) {
+	boxes, err := readISOBMFFBoxes(data, 0, len(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var meta *isobmffBox
+	for i := range boxes {
+		if boxes[i].boxType == "meta" {
+			meta = &boxes[i]
+			break
+		}
+	}
+	if meta == nil {
+		return data, nil
+	}
+	if meta.end-meta.start < 4 {
+		return nil, fmt.Errorf("truncated meta box")
+	}
+
+	// meta is a full box, its children follow the version and flags
+	children, err := readISOBMFFBoxes(data, meta.start+4, meta.end)
+	if err != nil {
+		return nil, err
+	}
+
+	var iinf, iloc, idat *isobmffBox
+	for i := range children {
+		switch children[i].boxType {
+		case "iinf":
+			iinf = &children[i]
+		case "iloc":
+			iloc = &children[i]
+		case "idat":
+			idat = &children[i]
+		}
+	}
+	if iinf == nil {
+		return data, nil
+	}
+
+	metadataItems, err := readISOBMFFMetadataItems(data, *iinf)
+	if err != nil {
+		return nil, err
+	}
+	if len(metadataItems) == 0 {
+		return data, nil
+	}
+	if iloc == nil {
+		return nil, fmt.Errorf("missing iloc box")
+	}
+
+	scrubbed := bytes.Clone(data)
+	err = forEachISOBMFFItemExtent(data, *iloc, func(itemID uint32, constructionMethod int, offset, length uint64) error {
+		if !metadataItems[itemID] {
+			return nil
+		}
+
+		start, end := uint64(0), uint64(len(data))
+		switch constructionMethod {
+		case 0:
+			// File offset
+		case 1:
+			if idat == nil {
+				return fmt.Errorf("missing idat box of item %d", itemID)
+			}
+			start, end = uint64(idat.start), uint64(idat.end)
+		default:
+			return fmt.Errorf("unsupported construction method %d of item %d", constructionMethod, itemID)
+		}
+
+		from := start + offset
+		to := end
+		if length > 0 {
+			to = from + length
+		}
+		if from > to || to > end {
+			return fmt.Errorf("invalid extent of item %d", itemID)
+		}
+		clear(scrubbed[from:to])
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return scrubbed, nil
+}
+
+// readISOBMFFMetadataItems returns the IDs of the EXIF and XMP items listed in the iinf box
+func readISOBMFFMetadataItems(data []byte, iinf isobmffBox) (map[uint32]bool, error) {
+	payload := data[iinf.start:iinf.end]
+	if len(payload) < 4 {
+		return nil, fmt.Errorf("truncated iinf box")
+	}
+
+	// Version and flags, then the entry count
+	entriesStart := 4 + 2
+	if payload[0] != 0 {
+		entriesStart = 4 + 4
+	}
+	if len(payload) < entriesStart {
+		return nil, fmt.Errorf("truncated iinf box")
+	}
+
+	entries, err := readISOBMFFBoxes(data, iinf.start+entriesStart, iinf.end)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[uint32]bool)
+	for _, entry := range entries {
+		if entry.boxType != "infe" {
+			continue
+		}
+
+		infe := data[entry.start:entry.end]
+		if len(infe) < 4 {
+			return nil, fmt.Errorf("truncated infe box")
+		}
+
+		var itemID uint32
+		rest := infe[4:]
+		switch infe[0] {
+		case 2:
+			if len(rest) < 2 {
+				return nil, fmt.Errorf("truncated infe box")
+			}
+			itemID, rest = uint32(binary.BigEndian.Uint16(rest)), rest[2:]
+		case 3:
+			if len(rest) < 4 {
+				return nil, fmt.Errorf("truncated infe box")
+			}
+			itemID, rest = binary.BigEndian.Uint32(rest), rest[4:]
+		default:
+			// Versions 0 and 1 have no item type, so metadata items cannot be told apart
+			return nil, fmt.Errorf("unsupported infe version %d", infe[0])
+		}
+
+		// Protection index, then the item type
+		if len(rest) < 6 {
+			return nil, fmt.Errorf("truncated infe box")
+		}
+		itemType, rest := string(rest[2:6]), rest[6:]
+
+		switch itemType {
+		case "Exif":
+			items[itemID] = true
+		case "mime":
+			// The item name, then the content type, both null-terminated
+			fields := bytes.SplitN(rest, []byte{0}, 3)
+			if len(fields) >= 2 && string(fields[1]) == "application/rdf+xml" {
+				items[itemID] = true
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// forEachISOBMFFItemExtent calls fn with every extent listed in the iloc box
+func forEachISOBMFFItemExtent(data []byte, iloc isobmffBox, fn func(itemID uint32, constructionMethod int, offset, length uint64) error) error {
+	r := &isobmffReader{data: data[iloc.start:iloc.end]}
+
+	version := int(r.uint(1))
+	r.uint(3)
+	sizes := r.uint(1)
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0x0F)
+	sizes = r.uint(1)
+	baseOffsetSize, indexSize := int(sizes>>4), 0
+	if version == 1 || version == 2 {
+		indexSize = int(sizes & 0x0F)
+	}
+
+	idSize := 2
+	if version == 2 {
+		idSize = 4
+	}
+
+	itemCount := r.uint(idSize)
+	for i := uint64(0); i < itemCount && r.err == nil; i++ {
+		itemID := uint32(r.uint(idSize))
+		constructionMethod := 0
+		if version == 1 || version == 2 {
+			constructionMethod = int(r.uint(2) & 0x0F)
+		}
+		r.uint(2)
+		baseOffset := r.uint(baseOffsetSize)
+
+		extentCount := r.uint(2)
+		for j := uint64(0); j < extentCount && r.err == nil; j++ {
+			r.uint(indexSize)
+			offset := r.uint(offsetSize)
+			length := r.uint(lengthSize)
+			if r.err != nil {
+				break
+			}
+			if err := fn(itemID, constructionMethod, baseOffset+offset, length); err != nil {
+				return err
+			}
+		}
+	}
+
+	return r.err
+}
+
+// isobmffReader reads big-endian integers of variable size and remembers the first error
+type isobmffReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+// uint reads an integer of 0, 1, 2, 3, 4 or 8 bytes
+func (r *isobmffReader) uint(size int) uint64 {
+	if r.err != nil {
+		return 0
+	}
+	if size != 0 && size != 1 && size != 2 && size != 3 && size != 4 && size != 8 {
+		r.err = fmt.Errorf("invalid field size %d", size)
+		return 0
+	}
+	if r.offset+size > len(r.data) {
+		r.err = fmt.Errorf("truncated iloc box")
+		return 0
+	}
+
+	var value uint64
+	for _, b := range r.data[r.offset : r.offset+size] {
+		value = value<<8 | uint64(b)
+	}
+	r.offset += size
+
+	return value
+}
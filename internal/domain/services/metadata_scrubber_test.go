@@ -0,0 +1,299 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/gen2brain/heic"
+	"golang.org/x/image/webp"
+)
+
+// gpsLatitude is the GPS latitude written into the test EXIF, 52°31'N
+const gpsLatitude = "GPS-52-31-N"
+
+// testExif returns a big-endian TIFF structure with an orientation tag and a GPS IFD
+func testExif(orientation uint16) []byte {
+	var b bytes.Buffer
+	b.WriteString("MM\x00\x2a")
+	binary.Write(&b, binary.BigEndian, uint32(8))
+
+	// IFD0 at offset 8: orientation and the GPS IFD pointer
+	gpsIFD := uint32(8 + 2 + 2*12 + 4)
+	binary.Write(&b, binary.BigEndian, uint16(2))
+	binary.Write(&b, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&b, binary.BigEndian, uint32(1))
+	binary.Write(&b, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(&b, binary.BigEndian, []uint16{0x8825, 4})
+	binary.Write(&b, binary.BigEndian, []uint32{1, gpsIFD})
+	binary.Write(&b, binary.BigEndian, uint32(0))
+
+	// GPS IFD: the latitude as an ASCII value stored after the IFD
+	value := gpsIFD + 2 + 12 + 4
+	binary.Write(&b, binary.BigEndian, uint16(1))
+	binary.Write(&b, binary.BigEndian, []uint16{0x0002, 2})
+	binary.Write(&b, binary.BigEndian, []uint32{uint32(len(gpsLatitude) + 1), value})
+	binary.Write(&b, binary.BigEndian, uint32(0))
+	b.WriteString(gpsLatitude + "\x00")
+
+	return b.Bytes()
+}
+
+// jpegWithMetadata inserts EXIF, XMP and comment segments after the SOI marker of the JPEG
+func jpegWithMetadata(t *testing.T, orientation uint16) []byte {
+	t.Helper()
+	data := readTestdata(t, "sample.jpg")
+
+	segment := func(marker byte, payload []byte) []byte {
+		header := []byte{0xFF, marker, 0, 0}
+		binary.BigEndian.PutUint16(header[2:], uint16(len(payload)+2))
+		return append(header, payload...)
+	}
+
+	var b bytes.Buffer
+	b.Write(data[:2])
+	b.Write(segment(0xE1, append([]byte("Exif\x00\x00"), testExif(orientation)...)))
+	b.Write(segment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta>"+gpsLatitude+"</x:xmpmeta>")))
+	b.Write(segment(0xFE, []byte("taken at "+gpsLatitude)))
+	b.Write(data[2:])
+	return b.Bytes()
+}
+
+// pngChunk encodes a PNG chunk with its CRC
+func pngChunk(chunkType string, payload []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, payload...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// pngWithMetadata inserts eXIf and textual chunks after the IHDR chunk of the PNG
+func pngWithMetadata(t *testing.T) []byte {
+	t.Helper()
+	data := readTestdata(t, "sample.png")
+	// Signature, then IHDR with its length, type and CRC
+	ihdrEnd := len(pngSignature) + 12 + int(binary.BigEndian.Uint32(data[len(pngSignature):]))
+
+	var b bytes.Buffer
+	b.Write(data[:ihdrEnd])
+	b.Write(pngChunk("eXIf", testExif(1)))
+	b.Write(pngChunk("tEXt", []byte("Location\x00"+gpsLatitude)))
+	b.Write(pngChunk("iTXt", []byte("XML:com.adobe.xmp\x00\x00\x00\x00\x00<x:xmpmeta>"+gpsLatitude+"</x:xmpmeta>")))
+	b.Write(data[ihdrEnd:])
+	return b.Bytes()
+}
+
+// webpChunk encodes a RIFF chunk padded to an even size
+func webpChunk(fourCC string, payload []byte) []byte {
+	chunk := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// webpWithMetadata converts the simple WebP to the extended format with EXIF and XMP chunks
+func webpWithMetadata(t *testing.T) []byte {
+	t.Helper()
+	data := readTestdata(t, "sample.webp")
+	config, err := webp.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Flags with EXIF and XMP present, reserved bytes and the canvas size minus one as 24-bit values
+	vp8x := []byte{0x08 | 0x04, 0, 0, 0}
+	vp8x = append(vp8x, binary.LittleEndian.AppendUint32(nil, uint32(config.Width-1))[:3]...)
+	vp8x = append(vp8x, binary.LittleEndian.AppendUint32(nil, uint32(config.Height-1))[:3]...)
+
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	body.Write(webpChunk("VP8X", vp8x))
+	body.Write(data[12:])
+	body.Write(webpChunk("EXIF", testExif(1)))
+	body.Write(webpChunk("XMP ", []byte("<x:xmpmeta>"+gpsLatitude+"</x:xmpmeta>")))
+
+	riff := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(body.Len()))...)
+	return append(riff, body.Bytes()...)
+}
+
+// isobmffTestBox encodes an ISO BMFF box, full boxes include their version and flags in the payload
+func isobmffTestBox(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	box = append(box, boxType...)
+	return append(box, body...)
+}
+
+// avifPayload stands in for the AV1 image data of the test AVIF
+var avifPayload = []byte("av01-image-data")
+
+// avifWithMetadata builds an AVIF file with an image item and EXIF and XMP items stored in mdat
+func avifWithMetadata() []byte {
+	infe := func(id uint16, itemType string, extra string) []byte {
+		payload := []byte{2, 0, 0, 0}
+		payload = binary.BigEndian.AppendUint16(payload, id)
+		payload = append(payload, 0, 0)
+		payload = append(payload, itemType...)
+		payload = append(payload, extra...)
+		return isobmffTestBox("infe", payload)
+	}
+
+	exif := append([]byte{0, 0, 0, 0}, testExif(1)...)
+	xmp := []byte("<x:xmpmeta>" + gpsLatitude + "</x:xmpmeta>")
+	items := [][]byte{avifPayload, exif, xmp}
+
+	ftyp := isobmffTestBox("ftyp", []byte("avif\x00\x00\x00\x00avifmif1miaf"))
+	hdlr := isobmffTestBox("hdlr", []byte("\x00\x00\x00\x00\x00\x00\x00\x00pict\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"))
+	iinf := isobmffTestBox("iinf", []byte{0, 0, 0, 0, 0, 3},
+		infe(1, "av01", "\x00"),
+		infe(2, "Exif", "\x00"),
+		infe(3, "mime", "XMP\x00application/rdf+xml\x00"))
+
+	// Version 1 with 4-byte offsets and lengths, the extents point into mdat
+	iloc := func(mdatStart int) []byte {
+		payload := []byte{1, 0, 0, 0, 0x44, 0x00}
+		payload = binary.BigEndian.AppendUint16(payload, uint16(len(items)))
+		offset := mdatStart
+		for i, item := range items {
+			payload = binary.BigEndian.AppendUint16(payload, uint16(i+1))
+			payload = append(payload, 0, 0, 0, 0)
+			payload = binary.BigEndian.AppendUint16(payload, 1)
+			payload = binary.BigEndian.AppendUint32(payload, uint32(offset))
+			payload = binary.BigEndian.AppendUint32(payload, uint32(len(item)))
+			offset += len(item)
+		}
+		return isobmffTestBox("iloc", payload)
+	}
+
+	meta := func(mdatStart int) []byte {
+		return isobmffTestBox("meta", []byte{0, 0, 0, 0}, hdlr, iinf, iloc(mdatStart))
+	}
+	mdatStart := len(ftyp) + len(meta(0)) + 8
+
+	return bytes.Join([][]byte{ftyp, meta(mdatStart), isobmffTestBox("mdat", items...)}, nil)
+}
+
+func TestScrubImageMetadataRemovesGPS(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		decode func([]byte) error
+	}{
+		{"jpeg", jpegWithMetadata(t, 1), func(data []byte) error {
+			_, err := jpeg.Decode(bytes.NewReader(data))
+			return err
+		}},
+		{"png", pngWithMetadata(t), func(data []byte) error {
+			_, err := png.Decode(bytes.NewReader(data))
+			return err
+		}},
+		{"webp", webpWithMetadata(t), func(data []byte) error {
+			_, err := webp.Decode(bytes.NewReader(data))
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !bytes.Contains(tt.data, []byte(gpsLatitude)) {
+				t.Fatal("test image has no GPS data")
+			}
+			if err := tt.decode(tt.data); err != nil {
+				t.Fatalf("test image does not decode: %v", err)
+			}
+
+			scrubbed, _, err := scrubImageMetadata(tt.data)
+			if err != nil {
+				t.Fatalf("scrubImageMetadata() error = %v", err)
+			}
+			if bytes.Contains(scrubbed, []byte(gpsLatitude)) {
+				t.Error("scrubbed image still contains the GPS data")
+			}
+			if bytes.Contains(scrubbed, []byte("Exif\x00\x00")) || bytes.Contains(scrubbed, []byte("eXIf")) || bytes.Contains(scrubbed, []byte("EXIF")) {
+				t.Error("scrubbed image still contains EXIF")
+			}
+			if err := tt.decode(scrubbed); err != nil {
+				t.Errorf("scrubbed image does not decode: %v", err)
+			}
+		})
+	}
+}
+
+func TestScrubImageMetadataRemovesAVIFMetadata(t *testing.T) {
+	data := avifWithMetadata()
+	if detectImageMIMEType(data) != "image/avif" {
+		t.Fatal("test image is not detected as AVIF")
+	}
+	if !bytes.Contains(data, []byte(gpsLatitude)) {
+		t.Fatal("test image has no GPS data")
+	}
+
+	scrubbed, _, err := scrubImageMetadata(data)
+	if err != nil {
+		t.Fatalf("scrubImageMetadata() error = %v", err)
+	}
+	if bytes.Contains(scrubbed, []byte(gpsLatitude)) || bytes.Contains(scrubbed, testExif(1)) {
+		t.Error("scrubbed image still contains the EXIF or XMP data")
+	}
+	if len(scrubbed) != len(data) || !bytes.Contains(scrubbed, avifPayload) {
+		t.Error("scrubbing changed the layout or the image data")
+	}
+}
+
+func TestScrubISOBMFFMetadataKeepsHEICDecodable(t *testing.T) {
+	data := readTestdata(t, "sample.heic")
+	scrubbed, err := scrubISOBMFFMetadata(data)
+	if err != nil {
+		t.Fatalf("scrubISOBMFFMetadata() error = %v", err)
+	}
+	if bytes.Equal(scrubbed, data) {
+		t.Error("the EXIF item of the HEIC image was not scrubbed")
+	}
+	if _, err := heic.Decode(bytes.NewReader(scrubbed)); err != nil {
+		t.Errorf("scrubbed image does not decode: %v", err)
+	}
+}
+
+func TestProcessUploadPassesAVIFThrough(t *testing.T) {
+	prepared, err := NewImagePreprocessor(32, 85).ProcessUpload(context.Background(), avifWithMetadata())
+	if err != nil {
+		t.Fatalf("ProcessUpload() error = %v", err)
+	}
+	if prepared.MIMEType != "image/avif" {
+		t.Errorf("ProcessUpload() MIME type = %q, want image/avif", prepared.MIMEType)
+	}
+	if bytes.Contains(prepared.Data, []byte(gpsLatitude)) || !bytes.Contains(prepared.Data, avifPayload) {
+		t.Error("ProcessUpload() did not pass the scrubbed AVIF image through")
+	}
+}
+
+func TestScrubImageMetadataKeepsJPEGOrientation(t *testing.T) {
+	scrubbed, orientation, err := scrubImageMetadata(jpegWithMetadata(t, 6))
+	if err != nil {
+		t.Fatalf("scrubImageMetadata() error = %v", err)
+	}
+	if orientation != 6 {
+		t.Errorf("orientation = %d, want 6", orientation)
+	}
+	if readJPEGOrientation(scrubbed) != 1 {
+		t.Error("scrubbed image still has an EXIF orientation")
+	}
+}
+
+func TestProcessUploadRejectsUndecodableHEIC(t *testing.T) {
+	// The ftyp and meta boxes, which hold the EXIF item, without the image data
+	truncated := readTestdata(t, "sample.heic")[:600]
+	if detectImageMIMEType(truncated) != "image/heic" {
+		t.Fatal("truncated image is not detected as HEIC")
+	}
+
+	if prepared, err := NewImagePreprocessor(0, 85).ProcessUpload(context.Background(), truncated); err == nil {
+		t.Errorf("ProcessUpload() = %q image, want an error instead of passing the metadata through", prepared.MIMEType)
+	}
+}
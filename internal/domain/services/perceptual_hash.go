@@ -0,0 +1,41 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// dHash computes the 64-bit difference hash of the image.
+// The image is shrunk to 9x8 grayscale pixels and every bit tells whether a pixel is brighter than its right neighbour,
+// so recompressed, resized or slightly brightened copies of a photo get the same hash.
+func dHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+// imageFingerprint returns the perceptual hash of a decoded image,
+// or a content hash for images that were passed through undecoded
+func imageFingerprint(prepared *PreparedImage) string {
+	if prepared.Image != nil {
+		return fmt.Sprintf("d%016x", dHash(prepared.Image))
+	}
+
+	sum := sha256.Sum256(prepared.Data)
+	return "s" + hex.EncodeToString(sum[:8])
+}
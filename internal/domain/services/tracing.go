@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/metrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/genai"
+)
+
+// startStage starts the span of a pipeline stage. The returned function ends the span
+// and records the duration of the stage in the metrics.
+func (s *WasteSortingService) startStage(ctx context.Context, name string) (context.Context, trace.Span, func()) {
+	ctx, span := s.tracer.Start(ctx, name)
+	start := time.Now()
+	return ctx, span, func() {
+		metrics.RecordStage(ctx, name, time.Since(start))
+		span.End()
+	}
+}
+
+// recordSpanError records the error on the span and marks the span as failed
+func recordSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// rejected marks the span of a stage that rejected the request and returns the error message type.
+// Rejections are client errors, so the span status is left unset.
+func rejected(span trace.Span, messageType string) string {
+	span.SetAttributes(attribute.String("request.rejected", messageType))
+	return messageType
+}
+
+// requestAttributes describes the request without identifying the user, the postal code is reduced to its region
+func requestAttributes(req *models.WasteSortingRequest) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("request.language", req.Language),
+		attribute.String("postal_code.prefix", postalCodePrefix(req.PostalCode)),
+		attribute.Bool("request.device_id", req.DeviceID != ""),
+	}
+}
+
+// postalCodePrefix returns the first two digits of the postal code, which identify its region
+func postalCodePrefix(postalCode string) string {
+	if len(postalCode) < 2 {
+		return ""
+	}
+	return postalCode[:2]
+}
+
+// recordGenerateContent annotates the span of a Gemini call with the GenAI semantic conventions:
+// the model, the token counts and the finish reasons of the candidates. The tokens are also counted in the metrics.
+func recordGenerateContent(ctx context.Context, model string, resp *genai.GenerateContentResponse) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.String("gen_ai.system", "vertex_ai"),
+		attribute.String("gen_ai.request.model", model),
+	)
+	if resp == nil {
+		return
+	}
+
+	if resp.ModelVersion != "" {
+		span.SetAttributes(attribute.String("gen_ai.response.model", resp.ModelVersion))
+	}
+	if usage := resp.UsageMetadata; usage != nil {
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", int(usage.PromptTokenCount)),
+			attribute.Int("gen_ai.usage.output_tokens", int(usage.CandidatesTokenCount)),
+			attribute.Int("gen_ai.usage.total_tokens", int(usage.TotalTokenCount)),
+		)
+		metrics.RecordTokens(ctx, model, metrics.TokenTypeInput, int(usage.PromptTokenCount))
+		metrics.RecordTokens(ctx, model, metrics.TokenTypeOutput, int(usage.CandidatesTokenCount))
+	}
+	finishReasons := make([]string, 0, len(resp.Candidates))
+	for _, candidate := range resp.Candidates {
+		if candidate != nil && candidate.FinishReason != "" {
+			finishReasons = append(finishReasons, string(candidate.FinishReason))
+		}
+	}
+	span.SetAttributes(attribute.StringSlice("gen_ai.response.finish_reasons", finishReasons))
+}
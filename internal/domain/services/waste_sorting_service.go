@@ -4,77 +4,494 @@ import (
 	"context"
 	"fmt"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/auth"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/barcode"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/tracing"
 	"io"
 	"mime/multipart"
-	"net/http"
 	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 
-	"google.golang.org/genai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WasteSortingService handles waste sorting business logic
 type WasteSortingService struct {
-	aiClient         *genai.Client
-	localization     *localization.Localizer
-	recaptchaService RecaptchaService
+	classifier        Classifier
+	translator        Translator
+	localization      *localization.Localizer
+	captchaVerifiers  CaptchaVerifiers
+	imagePreprocessor *ImagePreprocessor
+	imageLimits       ImageLimits
+	productCatalog    ProductCatalog
+	historyRepository HistoryRepository
+	experimentRouter  *ExperimentRouter
+	now               func() time.Time
+	logger            logging.Logger
+	tracer            tracing.Tracer
 }
 
-// RecaptchaService interface for reCAPTCHA verification
+// maxQueryLength is the maximum number of characters in a text query
+const maxQueryLength = 200
+
+// ImageLimits restricts the number and aggregate size of images per request
+type ImageLimits struct {
+	MaxImages     int
+	MaxTotalBytes int64
+}
+
+// RecaptchaService interface for reCAPTCHA verification, implemented by all bot protection providers
 type RecaptchaService interface {
 	VerifyToken(ctx context.Context, token string) (bool, error)
 }
 
-// NewWasteSortingService creates a new waste sorting service
-func NewWasteSortingService(aiClient *genai.Client, localizer *localization.Localizer, recaptchaService RecaptchaService) *WasteSortingService {
+// CaptchaProviderRecaptcha is the name of the reCAPTCHA Enterprise provider
+const CaptchaProviderRecaptcha = "recaptcha"
+
+// CaptchaVerifiers holds the interchangeable bot protection verifiers by provider name
+type CaptchaVerifiers struct {
+	Default   string
+	Verifiers map[string]RecaptchaService
+}
+
+// ProductCatalog interface for looking up packaging information by barcode
+type ProductCatalog interface {
+	Lookup(ctx context.Context, ean string) (*models.Product, error)
+}
+
+// NewWasteSortingService creates a new waste sorting service, now timestamps the history
+func NewWasteSortingService(classifier Classifier, translator Translator, localizer *localization.Localizer, captchaVerifiers CaptchaVerifiers, imagePreprocessor *ImagePreprocessor, imageLimits ImageLimits, productCatalog ProductCatalog, historyRepository HistoryRepository, experimentRouter *ExperimentRouter, now func() time.Time, logger logging.Logger, tracer tracing.Tracer) *WasteSortingService {
 	return &WasteSortingService{
-		aiClient:         aiClient,
-		localization:     localizer,
-		recaptchaService: recaptchaService,
+		classifier:        classifier,
+		translator:        translator,
+		localization:      localizer,
+		captchaVerifiers:  captchaVerifiers,
+		imagePreprocessor: imagePreprocessor,
+		imageLimits:       imageLimits,
+		productCatalog:    productCatalog,
+		historyRepository: historyRepository,
+		experimentRouter:  experimentRouter,
+		now:               now,
+		logger:            logger,
+		tracer:            tracer,
 	}
 }
 
 // ProcessWasteImage processes the waste sorting request
 func (s *WasteSortingService) ProcessWasteImage(ctx context.Context, req *models.WasteSortingRequest) (*models.WasteSortingResponse, error) {
-	// Validate postal code
-	if !s.isValidGermanPostalCode(req.PostalCode) {
-		return &models.WasteSortingResponse{
-			Success: false,
-			Error:   s.localization.GetErrorMessage(req.Language, "invalid_postal_code"),
-		}, nil
+	// Validate postal code, device ID and image files, then verify captcha
+	if messageType := s.admit(ctx, req, s.validateImageRequest); messageType != "" {
+		return s.errorResponse(req, messageType), nil
+	}
+
+	// Prepare images before they are classified
+	images, products, err := s.PrepareImages(ctx, req.Images)
+	if err != nil {
+		s.logger.Warning(ctx, map[string]interface{}{
+			"message": "Failed to prepare images",
+			"reason":  "invalid_image",
+			"error":   err.Error(),
+		})
+		return s.errorResponse(req, "invalid_image"), nil
+	}
+
+	return s.process(ctx, req, &ClassificationInput{
+		Images:     images,
+		Products:   products,
+		PostalCode: req.PostalCode,
+	}), nil
+}
+
+// ProcessTextQuery processes a waste sorting request that describes the item by name instead of an image
+func (s *WasteSortingService) ProcessTextQuery(ctx context.Context, req *models.WasteSortingRequest) (*models.WasteSortingResponse, error) {
+	// Validate postal code, device ID and item description, then verify captcha
+	if messageType := s.admit(ctx, req, s.validateTextRequest); messageType != "" {
+		return s.errorResponse(req, messageType), nil
+	}
+
+	return s.process(ctx, req, &ClassificationInput{
+		Query:      strings.TrimSpace(req.Query),
+		PostalCode: req.PostalCode,
+	}), nil
+}
+
+// admit validates the request and verifies its captcha.
+// It returns the error message type if the request is rejected, or an empty string.
+func (s *WasteSortingService) admit(ctx context.Context, req *models.WasteSortingRequest, validate func(context.Context, *models.WasteSortingRequest) string) string {
+	if messageType := validate(ctx, req); messageType != "" {
+		return messageType
 	}
 
-	// Validate image file
-	if !s.isValidImageFile(req.ImageHeader) {
-		return &models.WasteSortingResponse{
-			Success: false,
-			Error:   s.localization.GetErrorMessage(req.Language, "invalid_image"),
-		}, nil
+	return s.verifyClient(ctx, req.CaptchaProvider, req.RecaptchaCode)
+}
+
+// process classifies the input with the classifier of the request's experiment variant,
+// renders the result in the user's language and records it in the history of the device.
+// Image and text requests share it, so every stage runs the same way for both.
+func (s *WasteSortingService) process(ctx context.Context, req *models.WasteSortingRequest, input *ClassificationInput) *models.WasteSortingResponse {
+	subject := "item description"
+	if len(input.Images) > 0 {
+		subject = "images"
 	}
 
-	// Verify reCAPTCHA
-	isValid, err := s.recaptchaService.VerifyToken(ctx, req.RecaptchaCode)
-	if err != nil || !isValid {
-		return &models.WasteSortingResponse{
-			Success: false,
-			Error:   s.localization.GetErrorMessage(req.Language, "recaptcha_failed"),
-		}, nil
+	arm, variant := s.route(ctx, req)
+	classification, err := s.classify(ctx, arm.Classifier, input)
+	if err != nil {
+		s.logger.Error(ctx, map[string]interface{}{
+			"message": "Failed to classify " + subject,
+			"reason":  "processing_error",
+			"variant": variant,
+			"error":   err.Error(),
+		})
+		return s.errorResponse(req, "processing_error")
 	}
 
-	// Process image with Gemini AI
-	htmlResult, err := s.processImageWithGemini(ctx, req.ImageFile, req.PostalCode, req.Language)
+	htmlResult, err := s.renderHTML(ctx, arm.Translator, classification, req.Language)
 	if err != nil {
-		return &models.WasteSortingResponse{
-			Success: false,
-			Error:   s.localization.GetErrorMessage(req.Language, "processing_error"),
-		}, nil
+		s.logger.Error(ctx, map[string]interface{}{
+			"message": "Failed to render classification",
+			"reason":  "processing_error",
+			"error":   err.Error(),
+		})
+		return s.errorResponse(req, "processing_error")
 	}
 
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("prompt.classification", classification.PromptVersion))
+	classificationID := s.recordHistory(ctx, req, len(input.Images), classification, variant)
+
+	s.logger.Info(ctx, map[string]interface{}{
+		"message":           "Classified " + subject,
+		"category":          classification.Category,
+		"image_count":       len(input.Images),
+		"product_count":     len(input.Products),
+		"prompt_version":    classification.PromptVersion,
+		"variant":           variant,
+		"classification_id": classificationID,
+	})
+
 	return &models.WasteSortingResponse{
-		Success: true,
-		HTML:    htmlResult,
-	}, nil
+		Success:          true,
+		HTML:             htmlResult,
+		ClassificationID: classificationID,
+		PromptVersion:    classification.PromptVersion,
+		Variant:          variant,
+	}
+}
+
+// errorResponse returns a failed response with the error message in the user's language
+func (s *WasteSortingService) errorResponse(req *models.WasteSortingRequest, messageType string) *models.WasteSortingResponse {
+	return &models.WasteSortingResponse{
+		Success: false,
+		Error:   s.localization.GetErrorMessage(req.Language, messageType),
+	}
+}
+
+// validateImageRequest validates the postal code, the device ID and the image files.
+// It returns the error message type if the request is invalid, or an empty string.
+func (s *WasteSortingService) validateImageRequest(ctx context.Context, req *models.WasteSortingRequest) string {
+	ctx, span, end := s.startStage(ctx, "Validate request")
+	defer end()
+
+	var totalBytes int64
+	mimeTypes := make([]string, 0, len(req.Images))
+	for _, image := range req.Images {
+		if image.Header != nil {
+			totalBytes += image.Header.Size
+		}
+		mimeTypes = append(mimeTypes, uploadContentType(image.Header))
+	}
+	span.SetAttributes(requestAttributes(req)...)
+	span.SetAttributes(
+		attribute.Int("image.count", len(req.Images)),
+		attribute.Int64("image.bytes", totalBytes),
+		attribute.StringSlice("image.mime_types", mimeTypes),
+	)
+
+	if messageType := s.validateRequest(ctx, req); messageType != "" {
+		return rejected(span, messageType)
+	}
+
+	if len(req.Images) > s.imageLimits.MaxImages {
+		s.logger.Info(ctx, map[string]interface{}{
+			"message":     "Rejected request with too many images",
+			"reason":      "too_many_images",
+			"image_count": len(req.Images),
+			"max_images":  s.imageLimits.MaxImages,
+		})
+		return rejected(span, "too_many_images")
+	}
+
+	for _, image := range req.Images {
+		if !s.isValidImageFile(image.Header) {
+			s.logger.Info(ctx, map[string]interface{}{
+				"message":      "Rejected request with unsupported image type",
+				"reason":       "invalid_image",
+				"content_type": uploadContentType(image.Header),
+			})
+			return rejected(span, "invalid_image")
+		}
+	}
+	if totalBytes > s.imageLimits.MaxTotalBytes {
+		s.logger.Info(ctx, map[string]interface{}{
+			"message":     "Rejected request with too large images",
+			"reason":      "images_too_large",
+			"total_bytes": totalBytes,
+			"max_bytes":   s.imageLimits.MaxTotalBytes,
+		})
+		return rejected(span, "images_too_large")
+	}
+
+	return ""
+}
+
+// validateTextRequest validates the postal code, the device ID and the item description.
+// It returns the error message type if the request is invalid, or an empty string.
+func (s *WasteSortingService) validateTextRequest(ctx context.Context, req *models.WasteSortingRequest) string {
+	ctx, span, end := s.startStage(ctx, "Validate request")
+	defer end()
+
+	span.SetAttributes(requestAttributes(req)...)
+	span.SetAttributes(attribute.Int("query.length", utf8.RuneCountInString(req.Query)))
+
+	if messageType := s.validateRequest(ctx, req); messageType != "" {
+		return rejected(span, messageType)
+	}
+
+	if !s.isValidQuery(req.Query) {
+		s.logger.Info(ctx, map[string]interface{}{
+			"message": "Rejected request with invalid item description",
+			"reason":  "invalid_query",
+			"length":  utf8.RuneCountInString(req.Query),
+		})
+		return rejected(span, "invalid_query")
+	}
+
+	return ""
+}
+
+// validateRequest validates the fields shared by image and text requests
+func (s *WasteSortingService) validateRequest(ctx context.Context, req *models.WasteSortingRequest) string {
+	if !s.isValidGermanPostalCode(req.PostalCode) {
+		s.logger.Info(ctx, map[string]interface{}{
+			"message": "Rejected request with invalid postal code",
+			"reason":  "invalid_postal_code",
+		})
+		return "invalid_postal_code"
+	}
+
+	// The history is only recorded for devices that send an ID
+	if req.DeviceID != "" && !IsValidDeviceID(req.DeviceID) {
+		s.logger.Info(ctx, map[string]interface{}{
+			"message": "Rejected request with invalid device ID",
+			"reason":  "invalid_device_id",
+		})
+		return "invalid_device_id"
+	}
+
+	return ""
+}
+
+// Classify classifies the input with the default classifier, like requests outside of an experiment.
+// It is used by the evaluation to classify prepared images without a request.
+func (s *WasteSortingService) Classify(ctx context.Context, input *ClassificationInput) (*models.Classification, error) {
+	return s.classify(ctx, s.classifier, input)
+}
+
+// Translate translates the classification with the default translator.
+// Unlike requests, it does not fall back to the untranslated result, so the evaluation sees translation failures.
+func (s *WasteSortingService) Translate(ctx context.Context, classification *models.Classification, language string) (string, error) {
+	ctx, span, end := s.startStage(ctx, "Translate")
+	defer end()
+
+	span.SetAttributes(attribute.String("translation.language", language))
+
+	html, err := s.translator.Translate(ctx, classification, language)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return html, err
+}
+
+// classify classifies the input in a span of its own, the classifier annotates it with the model call
+func (s *WasteSortingService) classify(ctx context.Context, classifier Classifier, input *ClassificationInput) (*models.Classification, error) {
+	ctx, span, end := s.startStage(ctx, "Classify")
+	defer end()
+
+	span.SetAttributes(
+		attribute.Int("image.count", len(input.Images)),
+		attribute.Int("product.count", len(input.Products)),
+		attribute.String("postal_code.prefix", postalCodePrefix(input.PostalCode)),
+	)
+
+	classification, err := classifier.Classify(ctx, input)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.String("classification.category", classification.Category),
+		attribute.Int("classification.components", len(classification.Components)),
+	)
+	return classification, nil
+}
+
+// verifyClient checks that the request comes from an authenticated API client or a human solving a captcha.
+// The provider is taken from the API client if it has one, otherwise from the request, otherwise the default is used.
+// It returns the error message type if the verification failed, or an empty string.
+func (s *WasteSortingService) verifyClient(ctx context.Context, provider, token string) string {
+	ctx, span, end := s.startStage(ctx, "Verify captcha")
+	defer end()
+
+	if client := auth.ClientFromContext(ctx); client != nil {
+		if client.CaptchaProvider == "" {
+			span.SetAttributes(attribute.Bool("captcha.skipped", true))
+			s.logger.Debug(ctx, map[string]interface{}{
+				"message":   "Skipped captcha for API client",
+				"client_id": client.ID,
+			})
+			return ""
+		}
+		provider = client.CaptchaProvider
+	}
+	if provider == "" {
+		provider = s.captchaVerifiers.Default
+	}
+
+	span.SetAttributes(attribute.String("captcha.provider", provider))
+
+	messageType := "captcha_failed"
+	if provider == CaptchaProviderRecaptcha {
+		messageType = "recaptcha_failed"
+	}
+
+	verifier, exists := s.captchaVerifiers.Verifiers[provider]
+	if !exists {
+		s.logger.Warning(ctx, map[string]interface{}{
+			"message":  "Rejected request with unknown captcha provider",
+			"reason":   messageType,
+			"provider": provider,
+		})
+		return rejected(span, messageType)
+	}
+
+	// Verifiers with a risk score record it on the span as captcha.score
+	isValid, err := verifier.VerifyToken(ctx, token)
+	span.SetAttributes(attribute.Bool("captcha.valid", err == nil && isValid))
+	if err != nil {
+		recordSpanError(span, err)
+		s.logger.Error(ctx, map[string]interface{}{
+			"message":  "Failed to verify captcha",
+			"reason":   messageType,
+			"provider": provider,
+			"error":    err.Error(),
+		})
+		return messageType
+	}
+	if !isValid {
+		s.logger.Warning(ctx, map[string]interface{}{
+			"message":  "Rejected request with invalid captcha",
+			"reason":   messageType,
+			"provider": provider,
+		})
+		return rejected(span, messageType)
+	}
+	return ""
+}
+
+// recordHistory stores the classification in the history of the device and returns its ID for feedback.
+// Requests without a device ID are not stored, as nobody could delete them.
+// Failures are recorded on the trace only, as the user already has the result.
+func (s *WasteSortingService) recordHistory(ctx context.Context, req *models.WasteSortingRequest, imageCount int, classification *models.Classification, variant string) string {
+	if s.historyRepository == nil || req.DeviceID == "" {
+		return ""
+	}
+
+	ctx, span, end := s.startStage(ctx, "Save history")
+	defer end()
+
+	id, err := newHistoryID()
+	if err != nil {
+		recordSpanError(span, err)
+		s.logger.Error(ctx, map[string]interface{}{
+			"message": "Failed to create history ID",
+			"error":   err.Error(),
+		})
+		return ""
+	}
+
+	err = s.historyRepository.Save(ctx, &models.HistoryEntry{
+		ID:             id,
+		DeviceID:       req.DeviceID,
+		CreatedAt:      s.now().UTC(),
+		PostalCode:     req.PostalCode,
+		Language:       req.Language,
+		Query:          strings.TrimSpace(req.Query),
+		ImageCount:     imageCount,
+		Classification: classification,
+		Variant:        variant,
+	})
+	if err != nil {
+		recordSpanError(span, err)
+		s.logger.Error(ctx, map[string]interface{}{
+			"message": "Failed to save history entry",
+			"error":   err.Error(),
+		})
+		return ""
+	}
+
+	return id
+}
+
+// route returns the classifier and translator of the request's experiment variant and the variant label,
+// or the default classifier and translator if no experiment is running or the request is not part of it
+func (s *WasteSortingService) route(ctx context.Context, req *models.WasteSortingRequest) (*ExperimentArm, string) {
+	if s.experimentRouter != nil {
+		if variant, arm := s.experimentRouter.Route(ctx, req); arm != nil {
+			s.logger.Debug(ctx, map[string]interface{}{
+				"message": "Assigned request to experiment variant",
+				"variant": variant,
+			})
+			return arm, variant
+		}
+	}
+
+	return &ExperimentArm{Classifier: s.classifier, Translator: s.translator}, ""
+}
+
+// renderHTML translates the language-neutral classification into the user's language.
+// If the translation fails, the untranslated classification is rendered with the built-in template.
+func (s *WasteSortingService) renderHTML(ctx context.Context, translator Translator, classification *models.Classification, language string) (string, error) {
+	ctx, span, end := s.startStage(ctx, "Translate")
+	defer end()
+
+	span.SetAttributes(attribute.String("translation.language", language))
+
+	html, err := translator.Translate(ctx, classification, language)
+	if err == nil {
+		return html, nil
+	}
+
+	// The fallback keeps the request successful, so the error is recorded without failing the span
+	span.RecordError(err)
+	span.SetAttributes(attribute.Bool("translation.fallback", true))
+
+	s.logger.Warning(ctx, map[string]interface{}{
+		"message":  "Failed to translate classification, rendering the untranslated result",
+		"language": language,
+		"error":    err.Error(),
+	})
+
+	html, err = renderClassificationHTML(classification)
+	if err != nil {
+		recordSpanError(span, err)
+	}
+	return html, err
 }
 
 // isValidGermanPostalCode validates German postal codes (5 digits, 01001-99998)
@@ -93,19 +510,28 @@ func (s *WasteSortingService) isValidGermanPostalCode(postalCode string) bool {
 	return code >= 1001 && code <= 99998
 }
 
+// isValidQuery checks that the item description is non-empty and short enough to be a single item name
+func (s *WasteSortingService) isValidQuery(query string) bool {
+	query = strings.TrimSpace(query)
+	return query != "" && utf8.RuneCountInString(query) <= maxQueryLength
+}
+
 // isValidImageFile checks if the uploaded file is a valid image
 func (s *WasteSortingService) isValidImageFile(fileHeader *multipart.FileHeader) bool {
 	if fileHeader == nil {
 		return false
 	}
 
-	contentType := fileHeader.Header.Get("Content-Type")
+	contentType := uploadContentType(fileHeader)
 	validTypes := []string{
 		"image/jpeg",
 		"image/jpg",
 		"image/png",
 		"image/gif",
 		"image/webp",
+		"image/heic",
+		"image/heif",
+		"image/avif",
 	}
 
 	for _, validType := range validTypes {
@@ -116,77 +542,99 @@ func (s *WasteSortingService) isValidImageFile(fileHeader *multipart.FileHeader)
 	return false
 }
 
-// processImageWithGemini processes the image using Gemini AI
-func (s *WasteSortingService) processImageWithGemini(ctx context.Context, file multipart.File, postalCode, language string) (string, error) {
+// uploadContentType returns the declared content type of the uploaded file
+func uploadContentType(fileHeader *multipart.FileHeader) string {
+	if fileHeader == nil {
+		return ""
+	}
+	return fileHeader.Header.Get("Content-Type")
+}
+
+// PrepareImages reads, scrubs and preprocesses the uploaded images and looks up products by their barcodes
+func (s *WasteSortingService) PrepareImages(ctx context.Context, images []models.ImageUpload) ([]*PreparedImage, []*models.Product, error) {
+	ctx, span, end := s.startStage(ctx, "Prepare images")
+	defer end()
+
+	prepared := make([]*PreparedImage, 0, len(images))
+	var products []*models.Product
+
+	for i, image := range images {
+		preparedImage, product, err := s.prepareImage(ctx, i, image)
+		if err != nil {
+			recordSpanError(span, err)
+			return nil, nil, err
+		}
+		prepared = append(prepared, preparedImage)
+		if product != nil {
+			products = append(products, product)
+		}
+	}
+
+	span.SetAttributes(attribute.Int("product.count", len(products)))
+	return prepared, products, nil
+}
+
+// prepareImage reads and preprocesses a single uploaded image in a span of its own,
+// which the preprocessor annotates with the original and processed size and type
+func (s *WasteSortingService) prepareImage(ctx context.Context, index int, image models.ImageUpload) (*PreparedImage, *models.Product, error) {
+	ctx, span, end := s.startStage(ctx, "Prepare image")
+	defer end()
+
+	span.SetAttributes(
+		attribute.Int("image.index", index),
+		attribute.String("image.mime_type", uploadContentType(image.Header)),
+	)
+
 	// Read image data
-	imageData, err := io.ReadAll(file)
-	if err != nil {
-		return "", fmt.Errorf("failed to read image: %v", err)
-	}
-	mimeType := http.DetectContentType(imageData)
-
-	// Create prompt based on language
-	prompt := fmt.Sprintf(`Analyze this waste/garbage image and provide waste sorting instructions on Language %s for Germany, postal code %s.
-	Identify what type of waste this is and explain which bin it should go into (Restmüll, Gelbe Tonne/Gelber Sack, Papiertonne, Biotonne, Glass container, etc.).
-	Provide detailed instructions on how to sort this waste item, including any specific preparation steps (e.g., rinsing, removing labels).
-	Include specific local regulations for postal code %s if relevant.
-	Provide your response ONLY as valid HTML without any additional text, markdown, or explanations.
-	Use proper HTML structure with headings, paragraphs, and lists where appropriate.`, language, postalCode, postalCode)
-	contents := []*genai.Content{{
-		Parts: []*genai.Part{
-			{Text: prompt},
-			{InlineData: &genai.Blob{
-				Data:     imageData,
-				MIMEType: mimeType,
-			}},
-		},
-		Role: genai.RoleUser,
-	}}
-
-	resp, err := s.aiClient.Models.GenerateContent(ctx, "gemini-2.0-flash", contents, &genai.GenerateContentConfig{
-		SystemInstruction: &genai.Content{
-			Parts: []*genai.Part{{Text: "You are an expert in waste management and recycling regulations in Germany. You analyze waste items and provide detailed sorting instructions in the specified language. Your responses must be in valid HTML format only, without any additional text or markdown."}},
-		},
-	})
+	imageData, err := io.ReadAll(image.File)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %v", err)
+		err = fmt.Errorf("failed to read image: %v", err)
+		recordSpanError(span, err)
+		return nil, nil, err
 	}
+	span.SetAttributes(attribute.Int("image.bytes", len(imageData)))
 
-	if len(resp.Candidates) == 0 {
-		return "", fmt.Errorf("no response from Gemini")
+	preparedImage, err := s.imagePreprocessor.ProcessUpload(ctx, imageData)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, nil, err
 	}
 
-	for _, candidate := range resp.Candidates {
-		if candidate.Content == nil || len(candidate.Content.Parts) == 0 {
-			continue
-		}
-
-		var textResponse string
-		for _, part := range candidate.Content.Parts {
-			if part.Text != "" {
-				textResponse += part.Text
-			}
-		}
+	return preparedImage, s.lookupProduct(ctx, preparedImage), nil
+}
 
-		if textResponse == "" {
-			continue
-		}
+// lookupProduct decodes an EAN-13/UPC barcode in the image and looks up its packaging in the product catalog.
+// Lookup failures are not fatal, the image is then classified without packaging information.
+func (s *WasteSortingService) lookupProduct(ctx context.Context, prepared *PreparedImage) *models.Product {
+	if prepared.Image == nil {
+		return nil
+	}
 
-		re := regexp.MustCompile(`(?s)<body[^>]*>(.*?)</body>`)
-		match := re.FindStringSubmatch(textResponse)
-		if len(match) > 1 {
-			textResponse = match[1]
-		} else {
-			textResponse = ""
-		}
+	ean, found := barcode.DecodeEAN(prepared.Image)
+	if !found {
+		return nil
+	}
 
-		textResponse = strings.TrimSpace(textResponse)
-		if textResponse == "" {
-			continue
-		}
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("barcode.ean", ean))
 
-		return textResponse, nil
+	product, err := s.productCatalog.Lookup(ctx, ean)
+	if err != nil {
+		s.logger.Warning(ctx, map[string]interface{}{
+			"message": "Failed to look up product",
+			"ean":     ean,
+			"error":   err.Error(),
+		})
+	}
+	if err != nil || product == nil {
+		span.SetAttributes(attribute.Bool("barcode.product_found", false))
+		return nil
 	}
 
-	return "", fmt.Errorf("no valid text response from Gemini")
+	s.logger.Debug(ctx, map[string]interface{}{
+		"message": "Found product by barcode",
+		"ean":     ean,
+	})
+	span.SetAttributes(attribute.Bool("barcode.product_found", true))
+	return product
 }
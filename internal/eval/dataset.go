@@ -0,0 +1,122 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+)
+
+// LabelsFile is the name of the labels file in a dataset directory
+const LabelsFile = "labels.json"
+
+// Label describes an item of the dataset by its images or a text query,
+// and the bin its main component is expected in per postal code
+type Label struct {
+	Images   []string          `json:"images,omitempty"`
+	Query    string            `json:"query,omitempty"`
+	Category string            `json:"category,omitempty"`
+	Expected map[string]string `json:"expected"`
+}
+
+// Case is a single label evaluated for a single postal code
+type Case struct {
+	Label       *Label
+	PostalCode  string
+	ExpectedBin string
+}
+
+// ID identifies the case in reports, e.g. pizza-box.jpg@80331
+func (c *Case) ID() string {
+	subject := strings.Join(c.Label.Images, "+")
+	if subject == "" {
+		subject = "query:" + c.Label.Query
+	}
+	return subject + "@" + c.PostalCode
+}
+
+// Dataset is a directory of images with a labels file
+type Dataset struct {
+	Dir    string
+	Labels []*Label
+}
+
+// LoadDataset reads the labels file of the dataset directory
+func LoadDataset(dir string) (*Dataset, error) {
+	data, err := os.ReadFile(filepath.Join(dir, LabelsFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading labels: %v", err)
+	}
+
+	var labels []*Label
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("error parsing labels: %v", err)
+	}
+
+	for i, label := range labels {
+		if len(label.Images) == 0 && label.Query == "" {
+			return nil, fmt.Errorf("label %d has neither images nor a query", i)
+		}
+		if len(label.Expected) == 0 {
+			return nil, fmt.Errorf("label %d has no expected bins", i)
+		}
+	}
+
+	return &Dataset{Dir: dir, Labels: labels}, nil
+}
+
+// Cases returns a case per label and postal code, ordered by postal code within a label
+func (d *Dataset) Cases() []*Case {
+	var cases []*Case
+	for _, label := range d.Labels {
+		postalCodes := make([]string, 0, len(label.Expected))
+		for postalCode := range label.Expected {
+			postalCodes = append(postalCodes, postalCode)
+		}
+		sort.Strings(postalCodes)
+
+		for _, postalCode := range postalCodes {
+			cases = append(cases, &Case{
+				Label:       label,
+				PostalCode:  postalCode,
+				ExpectedBin: label.Expected[postalCode],
+			})
+		}
+	}
+
+	return cases
+}
+
+// OpenImage opens an image of the dataset as an upload, with the content type detected from its data
+func (d *Dataset) OpenImage(name string) (models.ImageUpload, error) {
+	file, err := os.Open(filepath.Join(d.Dir, name))
+	if err != nil {
+		return models.ImageUpload{}, fmt.Errorf("error opening image %s: %v", name, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return models.ImageUpload{}, fmt.Errorf("error reading image %s: %v", name, err)
+	}
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return models.ImageUpload{}, fmt.Errorf("error reading image %s: %v", name, err)
+	}
+
+	header := &multipart.FileHeader{Filename: name, Size: info.Size(), Header: textproto.MIMEHeader{}}
+	header.Header.Set("Content-Type", http.DetectContentType(head[:n]))
+
+	return models.ImageUpload{File: file, Header: header}, nil
+}
@@ -0,0 +1,163 @@
+package eval
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/services"
+)
+
+// Recordings holds classifier and translator responses keyed by their input,
+// so an evaluation can be repeated without calling the model
+type Recordings struct {
+	mu              sync.Mutex
+	Classifications map[string]*models.Classification `json:"classifications"`
+	Translations    map[string]string                 `json:"translations"`
+}
+
+// NewRecordings creates empty recordings
+func NewRecordings() *Recordings {
+	return &Recordings{
+		Classifications: map[string]*models.Classification{},
+		Translations:    map[string]string{},
+	}
+}
+
+// LoadRecordings reads recordings from the JSON file at path
+func LoadRecordings(path string) (*Recordings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading recordings: %v", err)
+	}
+
+	recordings := NewRecordings()
+	if err := json.Unmarshal(data, recordings); err != nil {
+		return nil, fmt.Errorf("error parsing recordings: %v", err)
+	}
+
+	return recordings, nil
+}
+
+// Save writes the recordings as JSON to path
+func (r *Recordings) Save(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding recordings: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing recordings: %v", err)
+	}
+
+	return nil
+}
+
+// Classifier returns a classifier answering from the recordings.
+// If next is not nil, unrecorded inputs are classified by next and recorded.
+func (r *Recordings) Classifier(next services.Classifier) services.Classifier {
+	return &recordedClassifier{recordings: r, next: next}
+}
+
+// Translator returns a translator answering from the recordings.
+// If next is not nil, unrecorded inputs are translated by next and recorded.
+func (r *Recordings) Translator(next services.Translator) services.Translator {
+	return &recordedTranslator{recordings: r, next: next}
+}
+
+type recordedClassifier struct {
+	recordings *Recordings
+	next       services.Classifier
+}
+
+// Classify returns the recorded classification of the input
+func (c *recordedClassifier) Classify(ctx context.Context, input *services.ClassificationInput) (*models.Classification, error) {
+	key := classificationKey(input)
+
+	c.recordings.mu.Lock()
+	classification, found := c.recordings.Classifications[key]
+	c.recordings.mu.Unlock()
+	if found {
+		return classification, nil
+	}
+	if c.next == nil {
+		return nil, fmt.Errorf("no recorded classification for %s", key)
+	}
+
+	classification, err := c.next.Classify(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	c.recordings.mu.Lock()
+	c.recordings.Classifications[key] = classification
+	c.recordings.mu.Unlock()
+
+	return classification, nil
+}
+
+type recordedTranslator struct {
+	recordings *Recordings
+	next       services.Translator
+}
+
+// Translate returns the recorded translation of the classification
+func (t *recordedTranslator) Translate(ctx context.Context, classification *models.Classification, language string) (string, error) {
+	key, err := translationKey(classification, language)
+	if err != nil {
+		return "", err
+	}
+
+	t.recordings.mu.Lock()
+	html, found := t.recordings.Translations[key]
+	t.recordings.mu.Unlock()
+	if found {
+		return html, nil
+	}
+	if t.next == nil {
+		return "", fmt.Errorf("no recorded translation for %s", key)
+	}
+
+	html, err = t.next.Translate(ctx, classification, language)
+	if err != nil {
+		return "", err
+	}
+
+	t.recordings.mu.Lock()
+	t.recordings.Translations[key] = html
+	t.recordings.mu.Unlock()
+
+	return html, nil
+}
+
+// classificationKey hashes the prepared images, the barcodes of the products found in them, the query and the postal code
+func classificationKey(input *services.ClassificationInput) string {
+	hash := sha256.New()
+	for _, image := range input.Images {
+		hash.Write(image.Data)
+	}
+	for _, product := range input.Products {
+		fmt.Fprintf(hash, "\x00%s", product.EAN)
+	}
+	fmt.Fprintf(hash, "\x00%s\x00%s", input.Query, input.PostalCode)
+
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// translationKey hashes the classification and appends the language
+func translationKey(classification *models.Classification, language string) (string, error) {
+	data, err := json.Marshal(classification)
+	if err != nil {
+		return "", fmt.Errorf("error encoding classification: %v", err)
+	}
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:16]) + ":" + language, nil
+}
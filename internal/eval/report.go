@@ -0,0 +1,248 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// failedBin stands for the prediction of a case that could not be classified
+const failedBin = "(failed)"
+
+// CaseResult is the outcome of a single case
+type CaseResult struct {
+	ID            string               `json:"id"`
+	Category      string               `json:"category,omitempty"`
+	ExpectedBin   string               `json:"expected_bin"`
+	PredictedBin  string               `json:"predicted_bin,omitempty"`
+	Correct       bool                 `json:"correct"`
+	PromptVersion string               `json:"prompt_version,omitempty"`
+	Translations  []*TranslationResult `json:"translations,omitempty"`
+	Error         string               `json:"error,omitempty"`
+}
+
+// TranslationResult is the outcome of translating a case into a language
+type TranslationResult struct {
+	Language     string `json:"language"`
+	Translated   bool   `json:"translated"`
+	BinMentioned bool   `json:"bin_mentioned"`
+	Error        string `json:"error,omitempty"`
+}
+
+// BinStats is the one-vs-rest confusion matrix of a bin
+type BinStats struct {
+	Bin            string  `json:"bin"`
+	TruePositives  int     `json:"true_positives"`
+	FalsePositives int     `json:"false_positives"`
+	FalseNegatives int     `json:"false_negatives"`
+	TrueNegatives  int     `json:"true_negatives"`
+	Precision      float64 `json:"precision"`
+	Recall         float64 `json:"recall"`
+}
+
+// LanguageCoverage counts the classified cases that were translated into a language
+// and whose translation still names the predicted bin
+type LanguageCoverage struct {
+	Language     string  `json:"language"`
+	Cases        int     `json:"cases"`
+	Translated   int     `json:"translated"`
+	BinMentioned int     `json:"bin_mentioned"`
+	Coverage     float64 `json:"coverage"`
+}
+
+// Report is the result of an evaluation run
+type Report struct {
+	GeneratedAt    time.Time                 `json:"generated_at"`
+	PromptVersions []string                  `json:"prompt_versions,omitempty"`
+	Cases          int                       `json:"cases"`
+	Correct        int                       `json:"correct"`
+	Failed         int                       `json:"failed"`
+	Accuracy       float64                   `json:"accuracy"`
+	Bins           []*BinStats               `json:"bins"`
+	Confusion      map[string]map[string]int `json:"confusion"`
+	Languages      []*LanguageCoverage       `json:"languages,omitempty"`
+	Results        []*CaseResult             `json:"results"`
+}
+
+func newReport(languages []string) *Report {
+	report := &Report{Confusion: map[string]map[string]int{}}
+	for _, language := range languages {
+		report.Languages = append(report.Languages, &LanguageCoverage{Language: language})
+	}
+	return report
+}
+
+// add counts the case result
+func (r *Report) add(result *CaseResult) {
+	predicted := result.PredictedBin
+	if result.Error != "" {
+		predicted = failedBin
+		r.Failed++
+	}
+	result.Correct = result.Error == "" && predicted == result.ExpectedBin
+
+	r.Cases++
+	if result.Correct {
+		r.Correct++
+	}
+	if r.Confusion[result.ExpectedBin] == nil {
+		r.Confusion[result.ExpectedBin] = map[string]int{}
+	}
+	r.Confusion[result.ExpectedBin][predicted]++
+
+	for _, translation := range result.Translations {
+		for _, coverage := range r.Languages {
+			if coverage.Language != translation.Language {
+				continue
+			}
+			coverage.Cases++
+			if translation.Translated {
+				coverage.Translated++
+			}
+			if translation.BinMentioned {
+				coverage.BinMentioned++
+			}
+		}
+	}
+
+	r.Results = append(r.Results, result)
+}
+
+// finish computes the rates and the per-bin confusion matrices
+func (r *Report) finish(generatedAt time.Time) {
+	r.GeneratedAt = generatedAt
+	r.Accuracy = ratio(r.Correct, r.Cases)
+
+	for _, coverage := range r.Languages {
+		coverage.Coverage = ratio(coverage.BinMentioned, coverage.Cases)
+	}
+
+	versions := map[string]bool{}
+	for _, result := range r.Results {
+		if result.PromptVersion != "" && !versions[result.PromptVersion] {
+			versions[result.PromptVersion] = true
+			r.PromptVersions = append(r.PromptVersions, result.PromptVersion)
+		}
+	}
+	sort.Strings(r.PromptVersions)
+
+	for _, bin := range r.bins() {
+		if bin == failedBin {
+			continue
+		}
+		stats := &BinStats{Bin: bin}
+		for expected, predictions := range r.Confusion {
+			for predicted, count := range predictions {
+				switch {
+				case expected == bin && predicted == bin:
+					stats.TruePositives += count
+				case predicted == bin:
+					stats.FalsePositives += count
+				case expected == bin:
+					stats.FalseNegatives += count
+				default:
+					stats.TrueNegatives += count
+				}
+			}
+		}
+		stats.Precision = ratio(stats.TruePositives, stats.TruePositives+stats.FalsePositives)
+		stats.Recall = ratio(stats.TruePositives, stats.TruePositives+stats.FalseNegatives)
+		r.Bins = append(r.Bins, stats)
+	}
+}
+
+// bins returns all expected and predicted bins in alphabetical order
+func (r *Report) bins() []string {
+	seen := map[string]bool{}
+	for expected, predictions := range r.Confusion {
+		seen[expected] = true
+		for predicted := range predictions {
+			seen[predicted] = true
+		}
+	}
+
+	bins := make([]string, 0, len(seen))
+	for bin := range seen {
+		bins = append(bins, bin)
+	}
+	sort.Strings(bins)
+
+	return bins
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteMarkdown writes the report as a Markdown document
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Classification Evaluation\n\n")
+	fmt.Fprintf(&b, "Generated %s", r.GeneratedAt.Format(time.RFC3339))
+	if len(r.PromptVersions) > 0 {
+		fmt.Fprintf(&b, " with %s", strings.Join(r.PromptVersions, ", "))
+	}
+	fmt.Fprintf(&b, ".\n\n")
+	fmt.Fprintf(&b, "**Accuracy: %.1f%%** (%d of %d cases correct, %d failed)\n\n", r.Accuracy*100, r.Correct, r.Cases, r.Failed)
+
+	fmt.Fprintf(&b, "## Bins\n\n")
+	fmt.Fprintf(&b, "| Bin | TP | FP | FN | TN | Precision | Recall |\n|---|---|---|---|---|---|---|\n")
+	for _, stats := range r.Bins {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %.2f | %.2f |\n",
+			stats.Bin, stats.TruePositives, stats.FalsePositives, stats.FalseNegatives, stats.TrueNegatives, stats.Precision, stats.Recall)
+	}
+
+	bins := r.bins()
+	fmt.Fprintf(&b, "\n## Confusion Matrix\n\nRows are expected bins, columns predicted bins.\n\n")
+	fmt.Fprintf(&b, "| Expected \\ Predicted | %s |\n|---|%s\n", strings.Join(bins, " | "), strings.Repeat("---|", len(bins)))
+	for _, expected := range bins {
+		if r.Confusion[expected] == nil {
+			continue
+		}
+		counts := make([]string, 0, len(bins))
+		for _, predicted := range bins {
+			counts = append(counts, fmt.Sprint(r.Confusion[expected][predicted]))
+		}
+		fmt.Fprintf(&b, "| %s | %s |\n", expected, strings.Join(counts, " | "))
+	}
+
+	if len(r.Languages) > 0 {
+		fmt.Fprintf(&b, "\n## Languages\n\n")
+		fmt.Fprintf(&b, "| Language | Cases | Translated | Bin mentioned | Coverage |\n|---|---|---|---|---|\n")
+		for _, coverage := range r.Languages {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %.1f%% |\n",
+				coverage.Language, coverage.Cases, coverage.Translated, coverage.BinMentioned, coverage.Coverage*100)
+		}
+	}
+
+	var mistakes []*CaseResult
+	for _, result := range r.Results {
+		if !result.Correct {
+			mistakes = append(mistakes, result)
+		}
+	}
+	if len(mistakes) > 0 {
+		fmt.Fprintf(&b, "\n## Incorrect Cases\n\n| Case | Expected | Predicted | Error |\n|---|---|---|---|\n")
+		for _, result := range mistakes {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", result.ID, result.ExpectedBin, result.PredictedBin, strings.ReplaceAll(result.Error, "|", "\\|"))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ratio returns part/total, or 0 if total is 0
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
@@ -0,0 +1,112 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/services"
+)
+
+// Runner classifies the cases of a dataset and translates the results into the languages.
+// Images go through the preprocessing and barcode lookup of the service like uploads.
+type Runner struct {
+	Service   *services.WasteSortingService
+	Languages []string
+}
+
+// Run evaluates all cases of the dataset. Failing cases are reported, not returned as errors.
+func (r *Runner) Run(ctx context.Context, dataset *Dataset) *Report {
+	report := newReport(r.Languages)
+
+	prepared := map[*Label]*preparedLabel{}
+	for _, evalCase := range dataset.Cases() {
+		result := &CaseResult{
+			ID:          evalCase.ID(),
+			Category:    evalCase.Label.Category,
+			ExpectedBin: evalCase.ExpectedBin,
+		}
+
+		label, found := prepared[evalCase.Label]
+		if !found {
+			var err error
+			label, err = r.prepareLabel(ctx, dataset, evalCase.Label)
+			if err != nil {
+				result.Error = err.Error()
+				report.add(result)
+				continue
+			}
+			prepared[evalCase.Label] = label
+		}
+
+		classification, err := r.Service.Classify(ctx, &services.ClassificationInput{
+			Images:     label.images,
+			Products:   label.products,
+			Query:      strings.TrimSpace(evalCase.Label.Query),
+			PostalCode: evalCase.PostalCode,
+		})
+		if err == nil && len(classification.Components) == 0 {
+			err = errors.New("classification has no components")
+		}
+		if err != nil {
+			result.Error = err.Error()
+			report.add(result)
+			continue
+		}
+
+		// The expected bin is the bin of the main component, which the model lists first
+		result.PredictedBin = classification.Components[0].Bin
+		result.PromptVersion = classification.PromptVersion
+
+		for _, language := range r.Languages {
+			translation := &TranslationResult{Language: language}
+			if text, err := r.Service.Translate(ctx, classification, language); err != nil {
+				translation.Error = err.Error()
+			} else {
+				// Translations keep the German bin names, so the result must still name the predicted bin
+				translation.Translated = strings.TrimSpace(text) != ""
+				translation.BinMentioned = strings.Contains(html.UnescapeString(text), result.PredictedBin)
+			}
+			result.Translations = append(result.Translations, translation)
+		}
+
+		report.add(result)
+	}
+
+	report.finish(time.Now().UTC())
+	return report
+}
+
+// preparedLabel holds the prepared images of a label and the products found by their barcodes
+type preparedLabel struct {
+	images   []*services.PreparedImage
+	products []*models.Product
+}
+
+// prepareLabel reads the images of the label and prepares them like uploads
+func (r *Runner) prepareLabel(ctx context.Context, dataset *Dataset, label *Label) (*preparedLabel, error) {
+	uploads := make([]models.ImageUpload, 0, len(label.Images))
+	defer func() {
+		for _, upload := range uploads {
+			upload.File.Close()
+		}
+	}()
+
+	for _, name := range label.Images {
+		upload, err := dataset.OpenImage(name)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+
+	images, products, err := r.Service.PrepareImages(ctx, uploads)
+	if err != nil {
+		return nil, err
+	}
+
+	return &preparedLabel{images: images, products: products}, nil
+}
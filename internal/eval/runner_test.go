@@ -0,0 +1,69 @@
+package eval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/services"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/catalog"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging/loggingtest"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/tracing/tracingtest"
+)
+
+// sampleDataset is the dataset shipped with the repository
+const sampleDataset = "../../testdata/eval"
+
+// newReplayRunner returns a runner answering from the recordings of the sample dataset
+func newReplayRunner(t *testing.T, catalogPath string) *Runner {
+	t.Helper()
+	recordings, err := LoadRecordings(sampleDataset + "/recordings.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	productCatalog, err := catalog.NewFileCatalog(catalogPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := services.NewWasteSortingService(recordings.Classifier(nil), recordings.Translator(nil), localization.NewLocalizer(), services.CaptchaVerifiers{},
+		services.NewImagePreprocessor(1536, 85), services.ImageLimits{MaxImages: 4, MaxTotalBytes: 20 << 20},
+		productCatalog, nil, nil, time.Now, loggingtest.NewLogger(), tracingtest.NewTracer())
+
+	return &Runner{Service: service, Languages: []string{"de", "en"}}
+}
+
+func TestRunnerReplaysSampleDataset(t *testing.T) {
+	dataset, err := LoadDataset(sampleDataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := newReplayRunner(t, sampleDataset+"/catalog.json").Run(context.Background(), dataset)
+
+	if report.Cases != 6 || report.Correct != 5 || report.Failed != 0 {
+		t.Errorf("report has %d cases, %d correct and %d failed, want 6, 5 and 0", report.Cases, report.Correct, report.Failed)
+	}
+	for _, result := range report.Results {
+		if result.ID == "query:pizza box@80331" && result.PredictedBin != "Restmüll" {
+			t.Errorf("pizza box predicted %q, want the recorded Restmüll", result.PredictedBin)
+		}
+	}
+}
+
+func TestRunnerLooksUpBarcodes(t *testing.T) {
+	dataset, err := LoadDataset(sampleDataset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the catalog the yogurt cup is classified without its packaging, which was not recorded
+	report := newReplayRunner(t, "").Run(context.Background(), dataset)
+
+	for _, result := range report.Results {
+		if result.ID == "yogurt-cup.png@10115" && result.Error == "" {
+			t.Error("yogurt cup without a catalog matched the recording made with its product")
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Client represents a partner integration authenticated by an API key
+type Client struct {
+	ID                string   `json:"id"`
+	Name              string   `json:"name"`
+	KeyHash           string   `json:"key_hash"`
+	AllowedOrigins    []string `json:"allowed_origins,omitempty"`
+	RequestsPerMinute int      `json:"requests_per_minute,omitempty"`
+	Burst             int      `json:"burst,omitempty"`
+	// CaptchaProvider makes browser-embedded clients, whose key is public, solve this captcha
+	CaptchaProvider string `json:"captcha_provider,omitempty"`
+	// Revoked rejects the key while keeping the client, so requests still sending it are logged with its ID
+	Revoked bool `json:"revoked,omitempty"`
+}
+
+// IsOriginAllowed checks the request origin against the client's allowed origins.
+// Server-to-server requests without an origin and clients without an allow-list are always allowed.
+func (c *Client) IsOriginAllowed(origin string) bool {
+	if origin == "" || len(c.AllowedOrigins) == 0 {
+		return true
+	}
+
+	for _, allowed := range c.AllowedOrigins {
+		if strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+type clientContextKey struct{}
+
+// WithClient returns a copy of the context carrying the authenticated client
+func WithClient(ctx context.Context, client *Client) context.Context {
+	return context.WithValue(ctx, clientContextKey{}, client)
+}
+
+// ClientFromContext returns the authenticated client, or nil for anonymous requests
+func ClientFromContext(ctx context.Context) *Client {
+	client, _ := ctx.Value(clientContextKey{}).(*Client)
+	return client
+}
+
+// HashKey returns the hex encoded SHA-256 hash under which API keys are stored
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKey returns the API key sent in the Authorization bearer or X-API-Key header
+func APIKey(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
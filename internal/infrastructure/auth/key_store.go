@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyStore defines the lookup of clients by the hash of their API key
+type KeyStore interface {
+	Lookup(ctx context.Context, keyHash string) (*Client, error)
+}
+
+// FileKeyStore is a key store backed by a local JSON file of clients.
+// Only key hashes are stored, so the file does not reveal the keys.
+type FileKeyStore struct {
+	clients map[string]*Client
+}
+
+// NewFileKeyStore loads the clients from the JSON file at path.
+// An empty path creates an empty store.
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	store := &FileKeyStore{clients: map[string]*Client{}}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading API key store: %v", err)
+	}
+
+	var clients []*Client
+	if err := json.Unmarshal(data, &clients); err != nil {
+		return nil, fmt.Errorf("error parsing API key store: %v", err)
+	}
+
+	for _, client := range clients {
+		store.clients[strings.ToLower(client.KeyHash)] = client
+	}
+
+	return store, nil
+}
+
+// Lookup returns the client with the key hash, or nil if the key is unknown
+func (s *FileKeyStore) Lookup(_ context.Context, keyHash string) (*Client, error) {
+	return s.clients[strings.ToLower(keyHash)], nil
+}
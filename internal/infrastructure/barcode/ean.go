@@ -0,0 +1,278 @@
+package barcode
+
+import (
+	"image"
+	"math"
+)
+
+// eanModules is the number of modules in an EAN-13/UPC-A symbol without quiet zones
+const eanModules = 95
+
+// eanRuns is the number of bars and spaces in an EAN-13/UPC-A symbol:
+// start guard, 6 left digits, middle guard, 6 right digits and end guard
+const eanRuns = 3 + 6*4 + 5 + 6*4 + 3
+
+// scanLines is the number of horizontal lines scanned across the image
+const scanLines = 24
+
+// minAgreeingLines is the number of scan lines that must decode the same code
+const minAgreeingLines = 2
+
+// maxDigitError is the maximum accumulated module width error for a digit match
+const maxDigitError = 1.6
+
+// lCodes holds the module widths of the odd parity (L) left digits, starting with a space.
+// R codes have the same widths starting with a bar, G codes are the L codes reversed.
+var lCodes = [10][4]int{
+	{3, 2, 1, 1}, {2, 2, 2, 1}, {2, 1, 2, 2}, {1, 4, 1, 1}, {1, 1, 3, 2},
+	{1, 2, 3, 1}, {1, 1, 1, 4}, {1, 3, 1, 2}, {1, 2, 1, 3}, {3, 1, 1, 2},
+}
+
+// firstDigitParities maps the L/G parity pattern of the left digits to the implied first digit,
+// with a set bit meaning G parity for the corresponding left digit
+var firstDigitParities = map[int]int{
+	0b000000: 0, 0b001011: 1, 0b001101: 2, 0b001110: 3, 0b010011: 4,
+	0b011001: 5, 0b011100: 6, 0b010101: 7, 0b010110: 8, 0b011010: 9,
+}
+
+// DecodeEAN looks for an EAN-13 or UPC-A barcode in the image and returns it as a 13 digit code.
+// UPC-A codes are returned with a leading zero, as they are a subset of EAN-13.
+func DecodeEAN(img image.Image) (string, bool) {
+	bounds := img.Bounds()
+	if bounds.Dx() < eanModules || bounds.Dy() == 0 {
+		return "", false
+	}
+
+	found := map[string]int{}
+	for line := 1; line <= scanLines; line++ {
+		y := bounds.Min.Y + bounds.Dy()*line/(scanLines+1)
+		runs, startsDark := runLengths(luminanceRow(img, y))
+
+		for _, reverse := range []bool{false, true} {
+			if reverse {
+				// The reversed row starts with the color the row ends with
+				reverseRuns(runs)
+				startsDark = startsDark == (len(runs)%2 == 1)
+			}
+			if code, ok := decodeRow(runs, startsDark); ok {
+				found[code]++
+				if found[code] >= minAgreeingLines {
+					return code, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// luminanceRow returns the luminance of every pixel in the row
+func luminanceRow(img image.Image, y int) []float64 {
+	bounds := img.Bounds()
+	row := make([]float64, 0, bounds.Dx())
+	for x := bounds.Min.X; x < bounds.Max.X; x++ {
+		r, g, b, _ := img.At(x, y).RGBA()
+		row = append(row, 0.299*float64(r)+0.587*float64(g)+0.114*float64(b))
+	}
+
+	return row
+}
+
+// reverseRuns reverses the runs in place to decode barcodes that are upside down
+func reverseRuns(runs []int) {
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+}
+
+// decodeRow tries every bar of the binarized row as the start of a symbol
+func decodeRow(runs []int, startsDark bool) (string, bool) {
+	for start := 0; start+eanRuns <= len(runs); start++ {
+		// The symbol starts with a bar, which is at every other run
+		if (start%2 == 0) != startsDark {
+			continue
+		}
+		// Require a quiet zone before the start guard
+		if start > 0 && runs[start-1] < runs[start]*3 {
+			continue
+		}
+		if code, ok := decodeSymbol(runs[start : start+eanRuns]); ok {
+			return code, true
+		}
+	}
+
+	return "", false
+}
+
+// runLengths converts the row to alternating dark and light run lengths using a threshold
+// halfway between the darkest and lightest pixel in a window around each pixel
+func runLengths(row []float64) ([]int, bool) {
+	lows, highs := windowExtremes(row, max(len(row)/8, 16))
+	dark := make([]bool, len(row))
+	for i := range row {
+		dark[i] = highs[i]-lows[i] > 0x2000 && row[i] < (lows[i]+highs[i])/2
+	}
+
+	var runs []int
+	for i := 0; i < len(dark); {
+		j := i
+		for j < len(dark) && dark[j] == dark[i] {
+			j++
+		}
+		runs = append(runs, j-i)
+		i = j
+	}
+
+	return runs, len(dark) > 0 && dark[0]
+}
+
+// windowExtremes returns the minimum and maximum of row[i-window:i+window] for every pixel i.
+// The candidates are kept in monotonic queues, so every pixel is added and removed once.
+func windowExtremes(row []float64, window int) ([]float64, []float64) {
+	lows := make([]float64, len(row))
+	highs := make([]float64, len(row))
+
+	var minQueue, maxQueue []int
+	next := 0
+	for i := range row {
+		for ; next < min(len(row), i+window); next++ {
+			for len(minQueue) > 0 && row[minQueue[len(minQueue)-1]] >= row[next] {
+				minQueue = minQueue[:len(minQueue)-1]
+			}
+			minQueue = append(minQueue, next)
+			for len(maxQueue) > 0 && row[maxQueue[len(maxQueue)-1]] <= row[next] {
+				maxQueue = maxQueue[:len(maxQueue)-1]
+			}
+			maxQueue = append(maxQueue, next)
+		}
+		for minQueue[0] < i-window {
+			minQueue = minQueue[1:]
+		}
+		for maxQueue[0] < i-window {
+			maxQueue = maxQueue[1:]
+		}
+
+		lows[i] = row[minQueue[0]]
+		highs[i] = row[maxQueue[0]]
+	}
+
+	return lows, highs
+}
+
+// decodeSymbol decodes the runs of a single symbol and validates its check digit
+func decodeSymbol(runs []int) (string, bool) {
+	total := 0
+	for _, run := range runs {
+		total += run
+	}
+	module := float64(total) / eanModules
+
+	// Guard bars and spaces are one module wide each, misreads that pass the checksum rarely keep them intact
+	if !isGuard(runs[:3], module) || !isGuard(runs[27:32], module) || !isGuard(runs[56:], module) {
+		return "", false
+	}
+
+	digits := make([]int, 13)
+	parity := 0
+	offset := 3
+	for i := 0; i < 6; i++ {
+		digit, gParity, ok := matchDigit(runs[offset:offset+4], true)
+		if !ok {
+			return "", false
+		}
+		digits[i+1] = digit
+		if gParity {
+			parity |= 1 << (5 - i)
+		}
+		offset += 4
+	}
+
+	// Skip the middle guard, it is validated above
+	offset += 5
+	for i := 0; i < 6; i++ {
+		digit, _, ok := matchDigit(runs[offset:offset+4], false)
+		if !ok {
+			return "", false
+		}
+		digits[i+7] = digit
+		offset += 4
+	}
+
+	first, ok := firstDigitParities[parity]
+	if !ok {
+		return "", false
+	}
+	digits[0] = first
+
+	if !validCheckDigit(digits) {
+		return "", false
+	}
+
+	code := make([]byte, len(digits))
+	for i, digit := range digits {
+		code[i] = byte('0' + digit)
+	}
+
+	return string(code), true
+}
+
+// isGuard checks that the runs of a guard pattern are one module wide each
+func isGuard(runs []int, module float64) bool {
+	for _, run := range runs {
+		if math.Abs(float64(run)/module-1) > 0.7 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchDigit finds the digit whose module widths are closest to the four runs.
+// Left digits may use L or G parity, right digits always use R codes.
+func matchDigit(runs []int, left bool) (int, bool, bool) {
+	width := 0
+	for _, run := range runs {
+		width += run
+	}
+	module := float64(width) / 7
+
+	bestDigit, bestG, bestError := -1, false, math.MaxFloat64
+	for digit, code := range lCodes {
+		candidates := []struct {
+			widths [4]int
+			g      bool
+		}{{code, false}}
+		if left {
+			candidates = append(candidates, struct {
+				widths [4]int
+				g      bool
+			}{[4]int{code[3], code[2], code[1], code[0]}, true})
+		}
+
+		for _, candidate := range candidates {
+			err := 0.0
+			for i, run := range runs {
+				err += math.Abs(float64(run)/module - float64(candidate.widths[i]))
+			}
+			if err < bestError {
+				bestDigit, bestG, bestError = digit, candidate.g, err
+			}
+		}
+	}
+
+	return bestDigit, bestG, bestError <= maxDigitError
+}
+
+// validCheckDigit validates the EAN-13 check digit
+func validCheckDigit(digits []int) bool {
+	sum := 0
+	for i, digit := range digits[:12] {
+		if i%2 == 0 {
+			sum += digit
+		} else {
+			sum += digit * 3
+		}
+	}
+
+	return (10-sum%10)%10 == digits[12]
+}
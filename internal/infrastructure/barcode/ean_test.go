@@ -0,0 +1,187 @@
+package barcode
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// eanModulePattern returns the 95 modules of the EAN-13 code, true for a bar.
+// The check digit is not validated, so codes with a bad checksum can be rendered.
+func eanModulePattern(t *testing.T, code string) []bool {
+	t.Helper()
+	return eanModulePatternWithGuards(t, code, "101", "01010", "101")
+}
+
+// eanModulePatternWithGuards returns the modules of the EAN-13 code with the given guard patterns
+func eanModulePatternWithGuards(t *testing.T, code, startGuard, middleGuard, endGuard string) []bool {
+	t.Helper()
+	if len(code) != 13 {
+		t.Fatalf("code %q is not 13 digits", code)
+	}
+	digits := make([]int, 13)
+	for i, c := range code {
+		digits[i] = int(c - '0')
+	}
+
+	parity := -1
+	for pattern, first := range firstDigitParities {
+		if first == digits[0] {
+			parity = pattern
+		}
+	}
+
+	var modules []bool
+	appendWidths := func(widths [4]int, bar bool) {
+		for _, width := range widths {
+			for i := 0; i < width; i++ {
+				modules = append(modules, bar)
+			}
+			bar = !bar
+		}
+	}
+	guard := func(pattern string) {
+		for _, c := range pattern {
+			modules = append(modules, c == '1')
+		}
+	}
+
+	guard(startGuard)
+	for i := 1; i <= 6; i++ {
+		widths := lCodes[digits[i]]
+		if parity&(1<<(6-i)) != 0 {
+			widths = [4]int{widths[3], widths[2], widths[1], widths[0]}
+		}
+		appendWidths(widths, false)
+	}
+	guard(middleGuard)
+	for i := 7; i <= 12; i++ {
+		appendWidths(lCodes[digits[i]], true)
+	}
+	guard(endGuard)
+
+	return modules
+}
+
+// renderEAN draws the code with a quiet zone of ten modules on a white background
+func renderEAN(t *testing.T, code string, moduleWidth, height int) *image.Gray {
+	t.Helper()
+	return renderModules(eanModulePattern(t, code), moduleWidth, height)
+}
+
+// renderModules draws the modules with a quiet zone of ten modules on a white background
+func renderModules(modules []bool, moduleWidth, height int) *image.Gray {
+	quietZone := 10 * moduleWidth
+	img := image.NewGray(image.Rect(0, 0, len(modules)*moduleWidth+2*quietZone, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			module := (x - quietZone) / moduleWidth
+			bar := x >= quietZone && module < len(modules) && modules[module]
+			if bar {
+				img.SetGray(x, y, color.Gray{Y: 20})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 235})
+			}
+		}
+	}
+
+	return img
+}
+
+// rotate180 turns the image upside down
+func rotate180(img *image.Gray) *image.Gray {
+	bounds := img.Bounds()
+	rotated := image.NewGray(bounds)
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			rotated.SetGray(bounds.Dx()-1-x, bounds.Dy()-1-y, img.GrayAt(x, y))
+		}
+	}
+	return rotated
+}
+
+func TestDecodeEAN(t *testing.T) {
+	tests := []struct {
+		name      string
+		img       image.Image
+		wantCode  string
+		wantFound bool
+	}{
+		{"ean-13", renderEAN(t, "4006381333931", 3, 60), "4006381333931", true},
+		{"ean-13 narrow modules", renderEAN(t, "5901234123457", 2, 40), "5901234123457", true},
+		{"upc-a with leading zero", renderEAN(t, "0036000291452", 4, 60), "0036000291452", true},
+		{"upside down", rotate180(renderEAN(t, "4006381333931", 3, 60)), "4006381333931", true},
+		{"bad check digit", renderEAN(t, "4006381333932", 3, 60), "", false},
+		{"wide middle guard", renderModules(eanModulePatternWithGuards(t, "4006381333931", "101", "0011001100", "101"), 3, 60), "", false},
+		{"wide end guard", renderModules(eanModulePatternWithGuards(t, "4006381333931", "101", "01010", "110011"), 3, 60), "", false},
+		{"blank image", image.NewGray(image.Rect(0, 0, 300, 60)), "", false},
+		{"too narrow", image.NewGray(image.Rect(0, 0, 50, 60)), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, found := DecodeEAN(tt.img)
+			if code != tt.wantCode || found != tt.wantFound {
+				t.Errorf("DecodeEAN() = %q, %v, want %q, %v", code, found, tt.wantCode, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestWindowExtremes(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	row := make([]float64, 500)
+	for i := range row {
+		row[i] = float64(rng.Intn(0x10000))
+	}
+
+	for _, window := range []int{1, 16, 62, 600} {
+		lows, highs := windowExtremes(row, window)
+		for i := range row {
+			lo, hi := math.MaxFloat64, 0.0
+			for j := max(0, i-window); j < min(len(row), i+window); j++ {
+				lo = min(lo, row[j])
+				hi = max(hi, row[j])
+			}
+			if lows[i] != lo || highs[i] != hi {
+				t.Fatalf("window %d, pixel %d: extremes = %v, %v, want %v, %v", window, i, lows[i], highs[i], lo, hi)
+			}
+		}
+	}
+}
+
+func TestRunLengths(t *testing.T) {
+	modules := eanModulePattern(t, "4006381333931")
+	row := make([]float64, 0, len(modules)+40)
+	for i := 0; i < 20; i++ {
+		row = append(row, 0xF000)
+	}
+	for _, bar := range modules {
+		if bar {
+			row = append(row, 0x1000)
+		} else {
+			row = append(row, 0xF000)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		row = append(row, 0xF000)
+	}
+
+	runs, startsDark := runLengths(row)
+	if startsDark {
+		t.Error("row starts dark, want light")
+	}
+	if len(runs) != eanRuns+2 {
+		t.Fatalf("got %d runs, want %d", len(runs), eanRuns+2)
+	}
+	var pattern strings.Builder
+	for _, run := range runs[1 : len(runs)-1] {
+		pattern.WriteByte(byte('0' + run))
+	}
+	if !strings.HasPrefix(pattern.String(), "111") || !strings.HasSuffix(pattern.String(), "111") {
+		t.Errorf("runs %s do not start and end with the guard bars", pattern.String())
+	}
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestMemoryStore returns a store whose clock is advanced by the returned function
+func newTestMemoryStore(capacity int) (*MemoryStore, func(time.Duration)) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore(capacity)
+	store.now = func() time.Time { return now }
+
+	return store, func(d time.Duration) {
+		now = now.Add(d)
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestMemoryStore(2)
+
+	store.Set(ctx, "a", "1", time.Minute)
+	store.Set(ctx, "b", "2", time.Minute)
+	// Reading a makes b the least recently used entry
+	if _, found, _ := store.Get(ctx, "a"); !found {
+		t.Fatal("Get(a) did not find the entry")
+	}
+	store.Set(ctx, "c", "3", time.Minute)
+
+	if _, found, _ := store.Get(ctx, "b"); found {
+		t.Error("Get(b) found the least recently used entry, want it evicted")
+	}
+	for key, want := range map[string]string{"a": "1", "c": "3"} {
+		if value, found, _ := store.Get(ctx, key); !found || value != want {
+			t.Errorf("Get(%s) = %q, %v, want %q", key, value, found, want)
+		}
+	}
+}
+
+func TestMemoryStoreUpdatesWithoutEvicting(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestMemoryStore(2)
+
+	store.Set(ctx, "a", "1", time.Minute)
+	store.Set(ctx, "b", "2", time.Minute)
+	store.Set(ctx, "a", "updated", time.Minute)
+
+	if value, found, _ := store.Get(ctx, "a"); !found || value != "updated" {
+		t.Errorf("Get(a) = %q, %v, want the updated value", value, found)
+	}
+	if _, found, _ := store.Get(ctx, "b"); !found {
+		t.Error("Get(b) did not find the entry, want it kept")
+	}
+}
+
+func TestMemoryStoreExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	store, advance := newTestMemoryStore(10)
+
+	store.Set(ctx, "short", "1", time.Minute)
+	store.Set(ctx, "long", "2", time.Hour)
+
+	advance(time.Minute - time.Second)
+	if _, found, _ := store.Get(ctx, "short"); !found {
+		t.Error("Get(short) did not find the entry before its expiry")
+	}
+
+	advance(time.Second)
+	if _, found, _ := store.Get(ctx, "short"); found {
+		t.Error("Get(short) found the entry at its expiry")
+	}
+	if _, found, _ := store.Get(ctx, "long"); !found {
+		t.Error("Get(long) did not find the entry before its expiry")
+	}
+	if len(store.entries) != 1 {
+		t.Errorf("store holds %d entries, want the expired entry removed", len(store.entries))
+	}
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewRedisStore(client, "test:"), server
+}
+
+func TestRedisStoreGetSet(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedisStore(t)
+
+	if _, found, err := store.Get(ctx, "missing"); err != nil || found {
+		t.Fatalf("Get(missing) = found %v, error %v, want not found", found, err)
+	}
+
+	if err := store.Set(ctx, "key", "value", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	value, found, err := store.Get(ctx, "key")
+	if err != nil || !found || value != "value" {
+		t.Fatalf("Get(key) = %q, %v, %v, want value", value, found, err)
+	}
+	if !server.Exists("test:key") {
+		t.Error("key is not stored with the prefix")
+	}
+}
+
+func TestRedisStoreExpiresAfterTTL(t *testing.T) {
+	ctx := context.Background()
+	store, server := newTestRedisStore(t)
+
+	if err := store.Set(ctx, "key", "value", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	server.FastForward(59 * time.Second)
+	if _, found, _ := store.Get(ctx, "key"); !found {
+		t.Fatal("key expired before its TTL")
+	}
+
+	server.FastForward(time.Second)
+	if _, found, _ := store.Get(ctx, "key"); found {
+		t.Error("key is still stored after its TTL")
+	}
+}
+
+func TestRedisStoreServerError(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	server.Close()
+
+	if _, _, err := store.Get(context.Background(), "key"); err == nil {
+		t.Error("Get() with the server down succeeded, want an error")
+	}
+}
@@ -0,0 +1,14 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Store defines a key-value store for cached results
+type Store interface {
+	// Get returns the value for the key and whether it was found
+	Get(ctx context.Context, key string) (string, bool, error)
+	// Set stores the value for the key for the given time to live
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+}
@@ -0,0 +1,69 @@
+package captcha
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// FriendlyCaptchaVerifyURL is the Friendly Captcha v2 siteverify endpoint hosted in the EU
+const FriendlyCaptchaVerifyURL = "https://eu.frcapi.com/api/v2/captcha/siteverify"
+
+// FriendlyCaptchaVerifier verifies Friendly Captcha v2 solutions
+type FriendlyCaptchaVerifier struct {
+	httpClient *http.Client
+	verifyURL  string
+	apiKey     string
+	siteKey    string
+}
+
+// NewFriendlyCaptchaVerifier creates a new Friendly Captcha verifier
+func NewFriendlyCaptchaVerifier(httpClient *http.Client, verifyURL, apiKey, siteKey string) *FriendlyCaptchaVerifier {
+	return &FriendlyCaptchaVerifier{
+		httpClient: httpClient,
+		verifyURL:  verifyURL,
+		apiKey:     apiKey,
+		siteKey:    siteKey,
+	}
+}
+
+// VerifyToken verifies the Friendly Captcha solution
+func (v *FriendlyCaptchaVerifier) VerifyToken(ctx context.Context, token string) (bool, error) {
+	if v.apiKey == "" {
+		return false, fmt.Errorf("missing Friendly Captcha configuration")
+	}
+
+	body, err := json.Marshal(map[string]string{"response": token, "sitekey": v.siteKey})
+	if err != nil {
+		return false, fmt.Errorf("error encoding Friendly Captcha request: %v", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("error creating Friendly Captcha request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-API-Key", v.apiKey)
+
+	response, err := v.httpClient.Do(request)
+	if err != nil {
+		return false, fmt.Errorf("error calling Friendly Captcha: %v", err)
+	}
+	defer response.Body.Close()
+
+	// Invalid solutions are answered with 200 and success false, other statuses are configuration errors
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("error from Friendly Captcha: status %d", response.StatusCode)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("error decoding Friendly Captcha response: %v", err)
+	}
+
+	return result.Success, nil
+}
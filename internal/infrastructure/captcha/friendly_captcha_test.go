@@ -0,0 +1,70 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newFriendlyCaptchaServer starts a Friendly Captcha v2 stand-in accepting validToken for the API key
+func newFriendlyCaptchaServer(t *testing.T, apiKey string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != apiKey {
+			http.Error(w, `{"success":false,"error":{"error_code":"auth_invalid"}}`, http.StatusUnauthorized)
+			return
+		}
+
+		var request struct {
+			Response string `json:"response"`
+			SiteKey  string `json:"sitekey"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if request.Response == validToken && request.SiteKey == "site-key" {
+			w.Write([]byte(`{"success":true,"data":{"challenge":{"origin":"example.com"}}}`))
+			return
+		}
+		w.Write([]byte(`{"success":false,"error":{"error_code":"response_invalid"}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestFriendlyCaptchaVerifier(t *testing.T) {
+	server := newFriendlyCaptchaServer(t, "api-key")
+	verifier := NewFriendlyCaptchaVerifier(server.Client(), server.URL, "api-key", "site-key")
+
+	valid, err := verifier.VerifyToken(context.Background(), validToken)
+	if err != nil || !valid {
+		t.Fatalf("VerifyToken(valid) = %v, %v, want true", valid, err)
+	}
+
+	valid, err = verifier.VerifyToken(context.Background(), "invalid-token")
+	if err != nil || valid {
+		t.Errorf("VerifyToken(invalid) = %v, %v, want false without error", valid, err)
+	}
+}
+
+func TestFriendlyCaptchaVerifierErrors(t *testing.T) {
+	server := newFriendlyCaptchaServer(t, "api-key")
+
+	if valid, err := NewFriendlyCaptchaVerifier(server.Client(), server.URL, "wrong-key", "site-key").VerifyToken(context.Background(), validToken); err == nil || valid {
+		t.Errorf("VerifyToken() with a rejected API key = %v, %v, want an error", valid, err)
+	}
+	if valid, err := NewFriendlyCaptchaVerifier(server.Client(), server.URL, "", "site-key").VerifyToken(context.Background(), validToken); err == nil || valid {
+		t.Errorf("VerifyToken() without an API key = %v, %v, want an error", valid, err)
+	}
+
+	errorServer := newErrorServer(t, http.StatusInternalServerError)
+	if valid, err := NewFriendlyCaptchaVerifier(errorServer.Client(), errorServer.URL, "api-key", "site-key").VerifyToken(context.Background(), validToken); err == nil || valid {
+		t.Errorf("VerifyToken() with a server error = %v, %v, want an error", valid, err)
+	}
+}
@@ -0,0 +1,43 @@
+package captcha
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// HCaptchaVerifyURL is the production hCaptcha siteverify endpoint
+const HCaptchaVerifyURL = "https://api.hcaptcha.com/siteverify"
+
+// HCaptchaVerifier verifies hCaptcha tokens
+type HCaptchaVerifier struct {
+	httpClient *http.Client
+	verifyURL  string
+	secret     string
+	siteKey    string
+}
+
+// NewHCaptchaVerifier creates a new hCaptcha verifier
+func NewHCaptchaVerifier(httpClient *http.Client, verifyURL, secret, siteKey string) *HCaptchaVerifier {
+	return &HCaptchaVerifier{
+		httpClient: httpClient,
+		verifyURL:  verifyURL,
+		secret:     secret,
+		siteKey:    siteKey,
+	}
+}
+
+// VerifyToken verifies the hCaptcha token
+func (v *HCaptchaVerifier) VerifyToken(ctx context.Context, token string) (bool, error) {
+	if v.secret == "" {
+		return false, fmt.Errorf("missing hCaptcha configuration")
+	}
+
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if v.siteKey != "" {
+		form.Set("sitekey", v.siteKey)
+	}
+
+	return postSiteVerify(ctx, v.httpClient, v.verifyURL, form)
+}
@@ -0,0 +1,38 @@
+package captcha
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestHCaptchaVerifier(t *testing.T) {
+	var received url.Values
+	server := newSiteVerifyServer(t, "secret", &received)
+	verifier := NewHCaptchaVerifier(server.Client(), server.URL, "secret", "site-key")
+
+	valid, err := verifier.VerifyToken(context.Background(), validToken)
+	if err != nil || !valid {
+		t.Fatalf("VerifyToken(valid) = %v, %v, want true", valid, err)
+	}
+	if received.Get("sitekey") != "site-key" {
+		t.Errorf("sitekey = %q, want site-key", received.Get("sitekey"))
+	}
+
+	valid, err = verifier.VerifyToken(context.Background(), "invalid-token")
+	if err != nil || valid {
+		t.Errorf("VerifyToken(invalid) = %v, %v, want false without error", valid, err)
+	}
+}
+
+func TestHCaptchaVerifierErrors(t *testing.T) {
+	server := newErrorServer(t, http.StatusInternalServerError)
+
+	if valid, err := NewHCaptchaVerifier(server.Client(), server.URL, "secret", "").VerifyToken(context.Background(), validToken); err == nil || valid {
+		t.Errorf("VerifyToken() with a server error = %v, %v, want an error", valid, err)
+	}
+	if valid, err := NewHCaptchaVerifier(server.Client(), server.URL, "", "").VerifyToken(context.Background(), validToken); err == nil || valid {
+		t.Errorf("VerifyToken() without a secret = %v, %v, want an error", valid, err)
+	}
+}
@@ -0,0 +1,42 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// siteVerifyResponse is the common part of the siteverify responses of hCaptcha and Turnstile
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// postSiteVerify posts the form to a siteverify endpoint and reports whether the token was accepted
+func postSiteVerify(ctx context.Context, httpClient *http.Client, verifyURL string, form url.Values) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, fmt.Errorf("error creating siteverify request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return false, fmt.Errorf("error calling siteverify: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("siteverify returned status %d", response.StatusCode)
+	}
+
+	var result siteVerifyResponse
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("error decoding siteverify response: %v", err)
+	}
+
+	return result.Success, nil
+}
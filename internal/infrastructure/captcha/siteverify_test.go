@@ -0,0 +1,49 @@
+package captcha
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// validToken is the only token the siteverify stand-ins accept
+const validToken = "valid-token"
+
+// newSiteVerifyServer starts a siteverify stand-in that accepts validToken for the secret.
+// The last received form is stored in received.
+func newSiteVerifyServer(t *testing.T, secret string, received *url.Values) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		*received = r.PostForm
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("secret") == secret && r.PostForm.Get("response") == validToken {
+			w.Write([]byte(`{"success":true}`))
+			return
+		}
+		w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// newErrorServer starts a stand-in answering every request with the status
+func newErrorServer(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, http.StatusText(status), status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
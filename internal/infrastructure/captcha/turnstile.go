@@ -0,0 +1,36 @@
+package captcha
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// TurnstileVerifyURL is the production Cloudflare Turnstile siteverify endpoint
+const TurnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+
+// TurnstileVerifier verifies Cloudflare Turnstile tokens
+type TurnstileVerifier struct {
+	httpClient *http.Client
+	verifyURL  string
+	secret     string
+}
+
+// NewTurnstileVerifier creates a new Turnstile verifier
+func NewTurnstileVerifier(httpClient *http.Client, verifyURL, secret string) *TurnstileVerifier {
+	return &TurnstileVerifier{
+		httpClient: httpClient,
+		verifyURL:  verifyURL,
+		secret:     secret,
+	}
+}
+
+// VerifyToken verifies the Turnstile token
+func (v *TurnstileVerifier) VerifyToken(ctx context.Context, token string) (bool, error) {
+	if v.secret == "" {
+		return false, fmt.Errorf("missing Turnstile configuration")
+	}
+
+	return postSiteVerify(ctx, v.httpClient, v.verifyURL, url.Values{"secret": {v.secret}, "response": {token}})
+}
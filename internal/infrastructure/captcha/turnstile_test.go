@@ -0,0 +1,40 @@
+package captcha
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestTurnstileVerifier(t *testing.T) {
+	var received url.Values
+	server := newSiteVerifyServer(t, "secret", &received)
+	verifier := NewTurnstileVerifier(server.Client(), server.URL, "secret")
+
+	valid, err := verifier.VerifyToken(context.Background(), validToken)
+	if err != nil || !valid {
+		t.Fatalf("VerifyToken(valid) = %v, %v, want true", valid, err)
+	}
+
+	valid, err = verifier.VerifyToken(context.Background(), "invalid-token")
+	if err != nil || valid {
+		t.Errorf("VerifyToken(invalid) = %v, %v, want false without error", valid, err)
+	}
+
+	valid, err = NewTurnstileVerifier(server.Client(), server.URL, "wrong-secret").VerifyToken(context.Background(), validToken)
+	if err != nil || valid {
+		t.Errorf("VerifyToken() with a wrong secret = %v, %v, want false without error", valid, err)
+	}
+}
+
+func TestTurnstileVerifierErrors(t *testing.T) {
+	server := newErrorServer(t, http.StatusBadGateway)
+
+	if valid, err := NewTurnstileVerifier(server.Client(), server.URL, "secret").VerifyToken(context.Background(), validToken); err == nil || valid {
+		t.Errorf("VerifyToken() with a server error = %v, %v, want an error", valid, err)
+	}
+	if valid, err := NewTurnstileVerifier(server.Client(), server.URL, "").VerifyToken(context.Background(), validToken); err == nil || valid {
+		t.Errorf("VerifyToken() without a secret = %v, %v, want an error", valid, err)
+	}
+}
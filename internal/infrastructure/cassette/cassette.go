@@ -0,0 +1,171 @@
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Redacted replaces secrets in recorded interactions
+const Redacted = "REDACTED"
+
+// redactedHeaders are never written to a cassette
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Goog-Api-Key", "X-Goog-User-Project"}
+
+// redactedParams are query parameters carrying credentials
+var redactedParams = []string{"key", "access_token"}
+
+// Request is a recorded request. The body is kept as a hash only, so uploaded images are not stored.
+type Request struct {
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Header   http.Header `json:"header,omitempty"`
+	BodyHash string      `json:"body_hash"`
+}
+
+// Response is a recorded response
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  *Request  `json:"request"`
+	Response *Response `json:"response"`
+}
+
+// Cassette is a sequence of recorded interactions
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Load reads the cassette at path
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %v", err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("error parsing cassette: %v", err)
+	}
+
+	return &c, nil
+}
+
+// Save writes the cassette to path
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cassette: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing cassette: %v", err)
+	}
+
+	return nil
+}
+
+// redactor removes credentials and the configured secrets, such as the project ID, from interactions.
+// Requests are redacted before matching, so a cassette recorded in one project replays in another.
+type redactor struct {
+	secrets []string
+}
+
+func newRedactor(secrets []string) *redactor {
+	r := &redactor{}
+	for _, secret := range secrets {
+		if secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+	return r
+}
+
+// request reads the body of req, restores it for the next transport and returns the redacted request
+func (r *redactor) request(req *http.Request) (*Request, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading request body: %v", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	u := *req.URL
+	query := u.Query()
+	for _, param := range redactedParams {
+		if query.Has(param) {
+			query.Set(param, Redacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	sum := sha256.Sum256([]byte(r.text(string(body))))
+
+	return &Request{
+		Method:   req.Method,
+		URL:      r.text(u.String()),
+		Header:   r.header(req.Header),
+		BodyHash: hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// response reads the body of resp, restores it for the caller and returns the redacted response
+func (r *redactor) response(resp *http.Response) (*Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %v", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     r.header(resp.Header),
+		Body:       r.text(string(body)),
+	}, nil
+}
+
+func (r *redactor) header(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for name, values := range header {
+		for _, value := range values {
+			redacted.Add(name, r.text(value))
+		}
+	}
+	for _, name := range redactedHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, Redacted)
+		}
+	}
+	return redacted
+}
+
+func (r *redactor) text(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+// key identifies a request when replaying
+func (req *Request) key() string {
+	return req.Method + " " + req.URL + " " + req.BodyHash
+}
+
+// errNoInteraction is returned by the replayer for requests missing from the cassette
+var errNoInteraction = errors.New("no recorded interaction")
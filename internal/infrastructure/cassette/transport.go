@@ -0,0 +1,131 @@
+package cassette
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Recorder is an http.RoundTripper that sends requests with the next transport
+// and records every interaction, redacted. The cassette file is written by Close.
+type Recorder struct {
+	mu       sync.Mutex
+	path     string
+	next     http.RoundTripper
+	redactor *redactor
+	cassette *Cassette
+}
+
+// NewRecorder creates a recorder writing to the cassette at path.
+// The secrets, such as the project ID, are replaced in URLs, headers and bodies.
+func NewRecorder(path string, next http.RoundTripper, secrets ...string) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Recorder{
+		path:     path,
+		next:     next,
+		redactor: newRedactor(secrets),
+		cassette: &Cassette{},
+	}
+}
+
+// RoundTrip sends the request and records it with its response
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := r.redactor.request(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.redactor.response(resp)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{Request: recorded, Response: response})
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// Close writes the recorded interactions to the cassette file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cassette.Save(r.path)
+}
+
+// Replayer is an http.RoundTripper answering requests from a cassette without network access.
+// Requests match by method, URL and body. Repeated requests are answered in recorded order,
+// and the last response is repeated once they are exhausted.
+type Replayer struct {
+	mu           sync.Mutex
+	redactor     *redactor
+	interactions map[string][]*Interaction
+	replayed     map[string]int
+}
+
+// NewReplayer loads the cassette at path. The secrets must match the ones used for recording.
+func NewReplayer(path string, secrets ...string) (*Replayer, error) {
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	replayer := &Replayer{
+		redactor:     newRedactor(secrets),
+		interactions: map[string][]*Interaction{},
+		replayed:     map[string]int{},
+	}
+	for _, interaction := range c.Interactions {
+		key := interaction.Request.key()
+		replayer.interactions[key] = append(replayer.interactions[key], interaction)
+	}
+
+	return replayer, nil
+}
+
+// RoundTrip returns the recorded response of the request
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := r.redactor.request(req)
+	if err != nil {
+		return nil, err
+	}
+	key := recorded.key()
+
+	r.mu.Lock()
+	interactions := r.interactions[key]
+	index := min(r.replayed[key], len(interactions)-1)
+	r.replayed[key]++
+	r.mu.Unlock()
+
+	if len(interactions) == 0 {
+		return nil, fmt.Errorf("%w for %s %s", errNoInteraction, recorded.Method, recorded.URL)
+	}
+	response := interactions[index].Response
+	// Redaction may have changed the length of the body
+	header := response.Header.Clone()
+	header.Del("Content-Length")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+		StatusCode:    response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(response.Body)),
+		ContentLength: int64(len(response.Body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorderWritesCassetteOnClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"text": "answer for my-project"}`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder := NewRecorder(path, nil, "my-project")
+	client := &http.Client{Transport: recorder}
+
+	for _, body := range []string{"first", "second"} {
+		response, err := client.Post(server.URL+"/v1/projects/my-project/models?key=secret", "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("cassette was written before Close")
+	}
+
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "my-project") || strings.Contains(string(data), "secret") {
+		t.Error("cassette contains the project ID or the API key")
+	}
+
+	replayer, err := NewReplayer(path, "other-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, server.URL+"/v1/projects/other-project/models?key=other", strings.NewReader("second"))
+	response, err := replayer.RoundTrip(r)
+	if err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	if want := `{"text": "answer for REDACTED"}`; string(body) != want {
+		t.Errorf("replayed body = %q, want %q", body, want)
+	}
+}
@@ -0,0 +1,53 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+)
+
+// FileCatalog is a product catalog backed by a local JSON file
+type FileCatalog struct {
+	products map[string]*models.Product
+}
+
+// NewFileCatalog loads the products from the JSON file at path.
+// An empty path creates an empty catalog.
+func NewFileCatalog(path string) (*FileCatalog, error) {
+	catalog := &FileCatalog{products: map[string]*models.Product{}}
+	if path == "" {
+		return catalog, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading product catalog: %v", err)
+	}
+
+	var products []*models.Product
+	if err := json.Unmarshal(data, &products); err != nil {
+		return nil, fmt.Errorf("error parsing product catalog: %v", err)
+	}
+
+	for _, product := range products {
+		catalog.products[normalizeEAN(product.EAN)] = product
+	}
+
+	return catalog, nil
+}
+
+// Lookup returns the product with the given EAN, or nil if it is not in the catalog
+func (c *FileCatalog) Lookup(_ context.Context, ean string) (*models.Product, error) {
+	return c.products[normalizeEAN(ean)], nil
+}
+
+// normalizeEAN pads UPC-A codes to 13 digits so both forms match the same product
+func normalizeEAN(ean string) string {
+	if len(ean) == 12 {
+		return "0" + ean
+	}
+	return ean
+}
@@ -1,26 +1,114 @@
 package config
 
 import (
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/captcha"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds application configuration
 type Config struct {
-	ProjectID        string
-	ApplicationName  string
-	RecaptchaSiteKey string
-	GCPEnabled       bool
-	LogLevel         int
+	ProjectID                   string
+	ApplicationName             string
+	RecaptchaSiteKey            string
+	CaptchaDefaultProvider      string
+	HCaptchaSecret              string
+	HCaptchaSiteKey             string
+	HCaptchaVerifyURL           string
+	TurnstileSecret             string
+	TurnstileVerifyURL          string
+	FriendlyCaptchaAPIKey       string
+	FriendlyCaptchaSiteKey      string
+	FriendlyCaptchaVerifyURL    string
+	GCPEnabled                  bool
+	MetricsOTLPEnabled          bool
+	LogLevel                    int
+	ImageMaxEdge                int
+	ImageJPEGQuality            int
+	MaxImages                   int
+	MaxImagesBytes              int64
+	CatalogPath                 string
+	GeminiModel                 string
+	ClassificationPromptVersion string
+	TranslationPromptVersion    string
+	ExperimentPath              string
+	TranslationModel            string
+	GeminiCassetteMode          string
+	GeminiCassettePath          string
+	CacheBackend                string
+	CacheTTL                    time.Duration
+	CacheSize                   int
+	RedisAddr                   string
+	IdempotencyBackend          string
+	IdempotencyWindow           time.Duration
+	IdempotencySize             int
+	RateLimitBackend            string
+	RateLimitIP                 int
+	RateLimitIPBurst            int
+	RateLimitKey                int
+	RateLimitKeyBurst           int
+	TrustedProxies              []string
+	HistoryBackend              string
+	HistoryDBPath               string
+	HistoryLimit                int
+	APIKeysPath                 string
+	CORSAllowedOrigins          []string
+	CORSAllowedHeaders          []string
+	CORSMaxAge                  int
 }
 
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
 	return &Config{
-		ProjectID:        getEnv("PROJECT_ID", "waste-tips"),
-		ApplicationName:  getEnv("APPLICATION_NAME", "Waste Tips"),
-		RecaptchaSiteKey: getEnv("RECAPTCHA_SITE_KEY", ""),
-		GCPEnabled:       getEnv("GCP_ENABLED", "true") == "true",
-		LogLevel:         100, // Default log level
+		ProjectID:                   getEnv("PROJECT_ID", "waste-tips"),
+		ApplicationName:             getEnv("APPLICATION_NAME", "Waste Tips"),
+		RecaptchaSiteKey:            getEnv("RECAPTCHA_SITE_KEY", ""),
+		CaptchaDefaultProvider:      getEnv("CAPTCHA_DEFAULT_PROVIDER", "recaptcha"),
+		HCaptchaSecret:              getEnv("HCAPTCHA_SECRET", ""),
+		HCaptchaSiteKey:             getEnv("HCAPTCHA_SITE_KEY", ""),
+		HCaptchaVerifyURL:           getEnv("HCAPTCHA_VERIFY_URL", captcha.HCaptchaVerifyURL),
+		TurnstileSecret:             getEnv("TURNSTILE_SECRET", ""),
+		TurnstileVerifyURL:          getEnv("TURNSTILE_VERIFY_URL", captcha.TurnstileVerifyURL),
+		FriendlyCaptchaAPIKey:       getEnv("FRIENDLY_CAPTCHA_API_KEY", ""),
+		FriendlyCaptchaSiteKey:      getEnv("FRIENDLY_CAPTCHA_SITE_KEY", ""),
+		FriendlyCaptchaVerifyURL:    getEnv("FRIENDLY_CAPTCHA_VERIFY_URL", captcha.FriendlyCaptchaVerifyURL),
+		GCPEnabled:                  getEnv("GCP_ENABLED", "true") == "true",
+		MetricsOTLPEnabled:          getEnv("METRICS_OTLP_ENABLED", "false") == "true",
+		LogLevel:                    100, // Default log level
+		ImageMaxEdge:                getEnvInt("IMAGE_MAX_EDGE", 1536),
+		ImageJPEGQuality:            getEnvInt("IMAGE_JPEG_QUALITY", 85),
+		MaxImages:                   getEnvInt("MAX_IMAGES", 4),
+		MaxImagesBytes:              int64(getEnvInt("MAX_IMAGES_BYTES", 20<<20)),
+		CatalogPath:                 getEnv("CATALOG_PATH", ""),
+		GeminiModel:                 getEnv("GEMINI_MODEL", "gemini-2.0-flash"),
+		ClassificationPromptVersion: getEnv("CLASSIFICATION_PROMPT_VERSION", "v1"),
+		TranslationPromptVersion:    getEnv("TRANSLATION_PROMPT_VERSION", "v1"),
+		ExperimentPath:              getEnv("EXPERIMENT_PATH", ""),
+		TranslationModel:            getEnv("TRANSLATION_MODEL", "gemini-2.0-flash-lite"),
+		GeminiCassetteMode:          getEnv("GEMINI_CASSETTE_MODE", ""),
+		GeminiCassettePath:          getEnv("GEMINI_CASSETTE_PATH", "testdata/gemini.cassette.json"),
+		CacheBackend:                getEnv("CACHE_BACKEND", "memory"),
+		CacheTTL:                    time.Duration(getEnvInt("CACHE_TTL_SECONDS", 86400)) * time.Second,
+		CacheSize:                   getEnvInt("CACHE_SIZE", 1000),
+		RedisAddr:                   getEnv("REDIS_ADDR", "localhost:6379"),
+		IdempotencyBackend:          getEnv("IDEMPOTENCY_BACKEND", "memory"),
+		IdempotencyWindow:           time.Duration(getEnvInt("IDEMPOTENCY_WINDOW_SECONDS", 86400)) * time.Second,
+		IdempotencySize:             getEnvInt("IDEMPOTENCY_SIZE", 1000),
+		RateLimitBackend:            getEnv("RATE_LIMIT_BACKEND", "memory"),
+		RateLimitIP:                 getEnvInt("RATE_LIMIT_IP_PER_MINUTE", 10),
+		RateLimitIPBurst:            getEnvInt("RATE_LIMIT_IP_BURST", 5),
+		RateLimitKey:                getEnvInt("RATE_LIMIT_KEY_PER_MINUTE", 120),
+		RateLimitKeyBurst:           getEnvInt("RATE_LIMIT_KEY_BURST", 30),
+		TrustedProxies:              getEnvList("TRUSTED_PROXIES", "10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,169.254.0.0/16,127.0.0.0/8,::1/128,fc00::/7"),
+		HistoryBackend:              getEnv("HISTORY_BACKEND", "none"),
+		HistoryDBPath:               getEnv("HISTORY_DB_PATH", "/tmp/history.db"),
+		HistoryLimit:                getEnvInt("HISTORY_LIMIT", 50),
+		APIKeysPath:                 getEnv("API_KEYS_PATH", ""),
+		CORSAllowedOrigins:          getEnvList("CORS_ALLOWED_ORIGINS", "*"),
+		CORSAllowedHeaders:          getEnvList("CORS_ALLOWED_HEADERS", "Content-Type,Authorization,X-API-Key,Idempotency-Key,X-Device-ID,traceparent,tracestate"),
+		CORSMaxAge:                  getEnvInt("CORS_MAX_AGE", 86400),
 	}
 }
 
@@ -30,3 +118,20 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -1,13 +1,26 @@
 package container
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/experiments"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/handlers"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/prompts"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/services"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/auth"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/cache"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/captcha"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/config"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/idempotency"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/localization"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/ratelimit"
 	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/recaptcha"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/tracing"
 	"github.com/DeryabinSergey/waste-tips-backend/libs/logger"
 	"github.com/DeryabinSergey/waste-tips-backend/libs/tracer"
 	"google.golang.org/genai"
@@ -16,61 +29,278 @@ import (
 // Container holds all application dependencies
 type Container struct {
 	Config              *config.Config
-	Logger              *logger.Log
-	Tracer              *tracer.Tracer
+	Logger              logging.Logger
+	Tracer              tracing.Tracer
 	Ai                  *genai.Client
 	Localizer           *localization.Localizer
-	RecaptchaService    *recaptcha.Service
+	RecaptchaService    services.RecaptchaService
+	ProductCatalog      services.ProductCatalog
+	Classifier          services.Classifier
+	Translator          services.Translator
+	HistoryRepository   services.HistoryRepository
+	KeyStore            auth.KeyStore
+	IPResolver          *ratelimit.IPResolver
+	RateLimiter         *ratelimit.Limiter
+	Deduplicator        *idempotency.Deduplicator
 	WasteSortingService *services.WasteSortingService
 	WasteSortingHandler *handlers.WasteSortingHandler
+	HistoryHandler      *handlers.HistoryHandler
+	FeedbackHandler     *handlers.FeedbackHandler
 }
 
-// NewContainer creates and initializes the dependency injection container
-func NewContainer(ctx context.Context) (*Container, error) {
-	cfg := config.LoadConfig()
+// NewContainer creates and initializes the dependency injection container.
+// Dependencies not replaced by the options are built from the environment configuration.
+func NewContainer(ctx context.Context, opts ...Option) (*Container, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	cfg := o.config
+	if cfg == nil {
+		cfg = config.LoadConfig()
+	}
+	now := o.now
+	if now == nil {
+		now = time.Now
+	}
 
 	// Initialize logger
-	l, err := logger.Init(ctx, cfg.ProjectID, cfg.ApplicationName, cfg.GCPEnabled, 100)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize logger: %w", err)
+	l := o.logger
+	if l == nil {
+		cloudLogger, err := logger.Init(ctx, cfg.ProjectID, cfg.ApplicationName, cfg.GCPEnabled, 100)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		l = cloudLogger
 	}
 
 	// Initialize tracer
-	tr, err := tracer.Init(ctx, cfg.ProjectID, cfg.ApplicationName, cfg.GCPEnabled)
-	if err != nil {
+	tr := o.tracer
+	if tr == nil {
+		cloudTracer, err := tracer.Init(ctx, cfg.ProjectID, cfg.ApplicationName, cfg.GCPEnabled)
+		if err != nil {
+			l.Critical(ctx, map[string]interface{}{
+				"message": "failed to initialize tracer",
+				"error":   err.Error(),
+			})
+			return nil, fmt.Errorf("failed to initialize tracer: %w", err)
+		}
+		tr = cloudTracer
+	}
+
+	// Initialize meter, the metrics are recorded through the global meter provider
+	if _, err := sharedMeter(cfg); err != nil {
 		l.Critical(ctx, map[string]interface{}{
-			"message": "failed to initialize tracer",
+			"message": "failed to initialize meter",
 			"error":   err.Error(),
 		})
-		return nil, fmt.Errorf("failed to initialize tracer: %w", err)
+		return nil, fmt.Errorf("failed to initialize meter: %w", err)
 	}
 
-	// Initialize Gemini client
-	geminiClient, err := genai.NewClient(ctx, &genai.ClientConfig{
-		HTTPOptions: genai.HTTPOptions{APIVersion: "v1"},
-		Backend:     genai.BackendVertexAI,
-		Project:     cfg.ProjectID,
-		Location:    "europe-west4",
-	})
+	// Load experiment if one is running, variants override the default models and prompts
+	experiment, err := sharedExperiment(cfg.ExperimentPath)
 	if err != nil {
 		l.Critical(ctx, map[string]interface{}{
-			"message": "failed to create Gemini client",
+			"message": "failed to load experiment",
 			"error":   err.Error(),
 		})
-		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+		return nil, fmt.Errorf("failed to load experiment: %w", err)
+	}
+
+	// Initialize Gemini client unless all models are replaced, recording or replaying its calls if configured
+	var geminiClient *genai.Client
+	if o.classifier == nil || o.translator == nil || experiment != nil {
+		geminiHTTPClient, geminiCredentials, err := sharedGeminiHTTPClient(cfg)
+		if err != nil {
+			l.Critical(ctx, map[string]interface{}{
+				"message": "failed to create Gemini HTTP client",
+				"error":   err.Error(),
+			})
+			return nil, fmt.Errorf("failed to create Gemini HTTP client: %w", err)
+		}
+		geminiClient, err = genai.NewClient(ctx, &genai.ClientConfig{
+			HTTPOptions: genai.HTTPOptions{APIVersion: "v1"},
+			Backend:     genai.BackendVertexAI,
+			Project:     cfg.ProjectID,
+			Location:    "europe-west4",
+			Credentials: geminiCredentials,
+			HTTPClient:  geminiHTTPClient,
+		})
+		if err != nil {
+			l.Critical(ctx, map[string]interface{}{
+				"message": "failed to create Gemini client",
+				"error":   err.Error(),
+			})
+			return nil, fmt.Errorf("failed to create Gemini client: %w", err)
+		}
 	}
 
 	// Initialize localizer
 	localizer := localization.NewLocalizer()
 
-	// Initialize reCAPTCHA service
-	recaptchaService := recaptcha.NewService(cfg.ProjectID, cfg.RecaptchaSiteKey)
+	// Initialize reCAPTCHA service and the alternative bot protection providers
+	var recaptchaService services.RecaptchaService = recaptcha.NewService(cfg.ProjectID, cfg.RecaptchaSiteKey)
+	captchaHTTPClient := &http.Client{Timeout: 10 * time.Second, Transport: tracer.Transport(nil)}
+	captchaVerifiers := services.CaptchaVerifiers{
+		Default: cfg.CaptchaDefaultProvider,
+		Verifiers: map[string]services.RecaptchaService{
+			services.CaptchaProviderRecaptcha: recaptchaService,
+			"hcaptcha":                        captcha.NewHCaptchaVerifier(captchaHTTPClient, cfg.HCaptchaVerifyURL, cfg.HCaptchaSecret, cfg.HCaptchaSiteKey),
+			"turnstile":                       captcha.NewTurnstileVerifier(captchaHTTPClient, cfg.TurnstileVerifyURL, cfg.TurnstileSecret),
+			"friendly_captcha":                captcha.NewFriendlyCaptchaVerifier(captchaHTTPClient, cfg.FriendlyCaptchaVerifyURL, cfg.FriendlyCaptchaAPIKey, cfg.FriendlyCaptchaSiteKey),
+		},
+	}
+	for provider, verifier := range o.captchaVerifiers {
+		captchaVerifiers.Verifiers[provider] = verifier
+	}
+	recaptchaService = captchaVerifiers.Verifiers[services.CaptchaProviderRecaptcha]
+
+	// Initialize product catalog
+	productCatalog := o.productCatalog
+	if productCatalog == nil {
+		fileCatalog, err := sharedProductCatalog(cfg.CatalogPath)
+		if err != nil {
+			l.Critical(ctx, map[string]interface{}{
+				"message": "failed to load product catalog",
+				"error":   err.Error(),
+			})
+			return nil, fmt.Errorf("failed to load product catalog: %w", err)
+		}
+		productCatalog = fileCatalog
+	}
+
+	// Initialize image preprocessor
+	imagePreprocessor := services.NewImagePreprocessor(cfg.ImageMaxEdge, cfg.ImageJPEGQuality)
+
+	// Initialize prompts selected by version
+	promptRegistry, err := prompts.NewRegistry()
+	if err != nil {
+		l.Critical(ctx, map[string]interface{}{
+			"message": "failed to load prompts",
+			"error":   err.Error(),
+		})
+		return nil, fmt.Errorf("failed to load prompts: %w", err)
+	}
+
+	// Initialize classifier and translator, cached unless caching is disabled.
+	// Items are classified once into a language-neutral result and translated per language.
+	cacheStore := o.cacheStore
+	if cacheStore == nil {
+		cacheStore = sharedCacheStore(cfg)
+	}
+	defaultArm := &services.ExperimentArm{Classifier: o.classifier, Translator: o.translator}
+	if defaultArm.Classifier == nil || defaultArm.Translator == nil {
+		geminiArm, err := newExperimentArm(cfg, geminiClient, promptRegistry, cacheStore, &experiments.Variant{})
+		if err != nil {
+			l.Critical(ctx, map[string]interface{}{
+				"message": "failed to configure classifier",
+				"error":   err.Error(),
+			})
+			return nil, fmt.Errorf("failed to configure classifier: %w", err)
+		}
+		defaultArm.Classifier = cmp.Or(defaultArm.Classifier, geminiArm.Classifier)
+		defaultArm.Translator = cmp.Or(defaultArm.Translator, geminiArm.Translator)
+	}
+
+	// Initialize experiment router if an experiment is running
+	var experimentRouter *services.ExperimentRouter
+	if experiment != nil {
+		arms := make(map[string]*services.ExperimentArm, len(experiment.Variants))
+		for _, variant := range experiment.Variants {
+			arms[variant.Name], err = newExperimentArm(cfg, geminiClient, promptRegistry, cacheStore, variant)
+			if err != nil {
+				l.Critical(ctx, map[string]interface{}{
+					"message": "failed to configure experiment variant",
+					"variant": variant.Name,
+					"error":   err.Error(),
+				})
+				return nil, fmt.Errorf("failed to configure experiment variant %s: %w", variant.Name, err)
+			}
+		}
+		experimentRouter = services.NewExperimentRouter(experiment, arms)
+	}
+
+	// Initialize history repository unless the history is disabled
+	var historyRepository services.HistoryRepository
+	historyStore := o.historyRepository
+	if historyStore == nil {
+		sqliteRepository, err := sharedHistoryRepository(cfg)
+		if err != nil {
+			l.Critical(ctx, map[string]interface{}{
+				"message": "failed to open history database",
+				"error":   err.Error(),
+			})
+			return nil, fmt.Errorf("failed to open history database: %w", err)
+		}
+		if sqliteRepository != nil {
+			historyStore = sqliteRepository
+		}
+	}
+	if historyStore != nil {
+		historyRepository = historyStore
+	}
 
 	// Initialize waste sorting service
-	wasteSortingService := services.NewWasteSortingService(geminiClient, localizer, recaptchaService)
+	wasteSortingService := services.NewWasteSortingService(defaultArm.Classifier, defaultArm.Translator, localizer, captchaVerifiers, imagePreprocessor, services.ImageLimits{
+		MaxImages:     cfg.MaxImages,
+		MaxTotalBytes: cfg.MaxImagesBytes,
+	}, productCatalog, historyRepository, experimentRouter, now, l, tr)
+
+	// Initialize API key store
+	keyStore := o.keyStore
+	if keyStore == nil {
+		fileKeyStore, err := sharedKeyStore(cfg.APIKeysPath)
+		if err != nil {
+			l.Critical(ctx, map[string]interface{}{
+				"message": "failed to load API key store",
+				"error":   err.Error(),
+			})
+			return nil, fmt.Errorf("failed to load API key store: %w", err)
+		}
+		keyStore = fileKeyStore
+	}
+
+	// Initialize client IP resolution, which honours X-Forwarded-For of trusted proxies
+	ipResolver, err := ratelimit.NewIPResolver(cfg.TrustedProxies)
+	if err != nil {
+		l.Critical(ctx, map[string]interface{}{
+			"message": "failed to parse trusted proxies",
+			"error":   err.Error(),
+		})
+		return nil, fmt.Errorf("failed to parse trusted proxies: %w", err)
+	}
+
+	// Initialize rate limiter unless rate limiting is disabled
+	var rateLimiter *ratelimit.Limiter
+	rateLimitStore := o.rateLimitStore
+	if rateLimitStore == nil {
+		rateLimitStore = sharedRateLimitStore(cfg)
+	}
+	if rateLimitStore != nil {
+		rateLimiter = ratelimit.NewLimiter(rateLimitStore,
+			ratelimit.PerMinute(cfg.RateLimitIP, cfg.RateLimitIPBurst),
+			ratelimit.PerMinute(cfg.RateLimitKey, cfg.RateLimitKeyBurst),
+			ipResolver,
+		)
+	}
+
+	// Initialize deduplicator unless idempotency keys are ignored
+	deduplicator := sharedDeduplicator(cfg)
+	if o.idempotencyStore != nil {
+		deduplicator = idempotency.NewDeduplicator(o.idempotencyStore, cfg.IdempotencyWindow)
+	}
 
 	// Initialize waste sorting handler
-	wasteSortingHandler := handlers.NewWasteSortingHandler(wasteSortingService, localizer)
+	wasteSortingHandler := handlers.NewWasteSortingHandler(wasteSortingService, localizer, l, tr)
+
+	// Initialize history and feedback handlers if the history is enabled, feedback references its entries
+	var historyHandler *handlers.HistoryHandler
+	var feedbackHandler *handlers.FeedbackHandler
+	if historyStore != nil {
+		historyHandler = handlers.NewHistoryHandler(services.NewHistoryService(historyStore, localizer, cfg.HistoryLimit, l), localizer)
+		feedbackHandler = handlers.NewFeedbackHandler(services.NewFeedbackService(historyStore, historyStore, localizer, now, l), localizer)
+	}
 
 	return &Container{
 		Config:              cfg,
@@ -79,7 +309,42 @@ func NewContainer(ctx context.Context) (*Container, error) {
 		Ai:                  geminiClient,
 		Localizer:           localizer,
 		RecaptchaService:    recaptchaService,
+		ProductCatalog:      productCatalog,
+		Classifier:          defaultArm.Classifier,
+		Translator:          defaultArm.Translator,
+		HistoryRepository:   historyRepository,
+		KeyStore:            keyStore,
+		IPResolver:          ipResolver,
+		RateLimiter:         rateLimiter,
+		Deduplicator:        deduplicator,
 		WasteSortingService: wasteSortingService,
 		WasteSortingHandler: wasteSortingHandler,
+		HistoryHandler:      historyHandler,
+		FeedbackHandler:     feedbackHandler,
 	}, nil
 }
+
+// newExperimentArm creates the classifier and translator of the variant, falling back to the configured
+// models and prompt versions. Cached results are kept per prompt version and model.
+func newExperimentArm(cfg *config.Config, geminiClient *genai.Client, promptRegistry *prompts.Registry, cacheStore cache.Store, variant *experiments.Variant) (*services.ExperimentArm, error) {
+	model := cmp.Or(variant.Model, cfg.GeminiModel)
+	translationModel := cmp.Or(variant.TranslationModel, cfg.TranslationModel)
+
+	classificationPrompt, err := promptRegistry.Get(prompts.Classification, cmp.Or(variant.ClassificationPrompt, cfg.ClassificationPromptVersion))
+	if err != nil {
+		return nil, err
+	}
+	translationPrompt, err := promptRegistry.Get(prompts.Translation, cmp.Or(variant.TranslationPrompt, cfg.TranslationPromptVersion))
+	if err != nil {
+		return nil, err
+	}
+
+	var classifier services.Classifier = services.NewGeminiClassifier(geminiClient, model, classificationPrompt)
+	var translator services.Translator = services.NewGeminiTranslator(geminiClient, translationModel, translationPrompt)
+	if cacheStore != nil {
+		classifier = services.NewCachingClassifier(classifier, cacheStore, cfg.CacheTTL, classificationPrompt.ID()+"/"+model)
+		translator = services.NewCachingTranslator(translator, cacheStore, cfg.CacheTTL, translationPrompt.ID()+"/"+translationModel)
+	}
+
+	return &services.ExperimentArm{Classifier: classifier, Translator: translator}, nil
+}
@@ -0,0 +1,137 @@
+package container
+
+import (
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/services"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/auth"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/cache"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/config"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/logging"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/ratelimit"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/tracing"
+)
+
+// HistoryStore stores the classification history and the feedback on it
+type HistoryStore interface {
+	services.HistoryRepository
+	services.FeedbackRepository
+}
+
+// Option replaces a dependency the container would otherwise build from the configuration
+type Option func(*options)
+
+type options struct {
+	config            *config.Config
+	logger            logging.Logger
+	tracer            tracing.Tracer
+	classifier        services.Classifier
+	translator        services.Translator
+	captchaVerifiers  map[string]services.RecaptchaService
+	productCatalog    services.ProductCatalog
+	now               func() time.Time
+	cacheStore        cache.Store
+	rateLimitStore    ratelimit.Store
+	idempotencyStore  cache.Store
+	historyRepository HistoryStore
+	keyStore          auth.KeyStore
+}
+
+// WithConfig uses the configuration instead of reading it from the environment
+func WithConfig(cfg *config.Config) Option {
+	return func(o *options) {
+		o.config = cfg
+	}
+}
+
+// WithLogger uses the logger instead of Cloud Logging
+func WithLogger(logger logging.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithTracer uses the tracer instead of Cloud Trace
+func WithTracer(tracer tracing.Tracer) Option {
+	return func(o *options) {
+		o.tracer = tracer
+	}
+}
+
+// WithClassifier uses the classifier instead of Gemini. Experiment variants still use Gemini.
+func WithClassifier(classifier services.Classifier) Option {
+	return func(o *options) {
+		o.classifier = classifier
+	}
+}
+
+// WithTranslator uses the translator instead of Gemini. Experiment variants still use Gemini.
+func WithTranslator(translator services.Translator) Option {
+	return func(o *options) {
+		o.translator = translator
+	}
+}
+
+// WithCaptchaVerifier uses the verifier for the bot protection provider, such as recaptcha or turnstile
+func WithCaptchaVerifier(provider string, verifier services.RecaptchaService) Option {
+	return func(o *options) {
+		if o.captchaVerifiers == nil {
+			o.captchaVerifiers = map[string]services.RecaptchaService{}
+		}
+		o.captchaVerifiers[provider] = verifier
+	}
+}
+
+// WithRecaptchaService uses the service instead of reCAPTCHA Enterprise, like WithCaptchaVerifier for the recaptcha provider
+func WithRecaptchaService(service services.RecaptchaService) Option {
+	return WithCaptchaVerifier(services.CaptchaProviderRecaptcha, service)
+}
+
+// WithCatalog looks up products in the catalog instead of the configured catalog file
+func WithCatalog(productCatalog services.ProductCatalog) Option {
+	return func(o *options) {
+		o.productCatalog = productCatalog
+	}
+}
+
+// WithClock uses now for the timestamps of classifications and feedback
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// WithCacheStore caches classifications and translations in the store instead of the configured backend
+func WithCacheStore(store cache.Store) Option {
+	return func(o *options) {
+		o.cacheStore = store
+	}
+}
+
+// WithRateLimitStore keeps the token buckets in the store instead of the configured backend
+func WithRateLimitStore(store ratelimit.Store) Option {
+	return func(o *options) {
+		o.rateLimitStore = store
+	}
+}
+
+// WithIdempotencyStore keeps the responses to idempotent requests in the store instead of the configured backend
+func WithIdempotencyStore(store cache.Store) Option {
+	return func(o *options) {
+		o.idempotencyStore = store
+	}
+}
+
+// WithHistoryRepository stores the history and feedback in the repository instead of the configured database
+func WithHistoryRepository(repository HistoryStore) Option {
+	return func(o *options) {
+		o.historyRepository = repository
+	}
+}
+
+// WithKeyStore authenticates API keys with the store instead of the configured key file
+func WithKeyStore(keyStore auth.KeyStore) Option {
+	return func(o *options) {
+		o.keyStore = keyStore
+	}
+}
@@ -0,0 +1,321 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	googleauth "cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials"
+	"cloud.google.com/go/auth/httptransport"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/experiments"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/auth"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/cache"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/cassette"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/catalog"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/config"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/history"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/idempotency"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/ratelimit"
+	"github.com/DeryabinSergey/waste-tips-backend/libs/meter"
+	"github.com/DeryabinSergey/waste-tips-backend/libs/tracer"
+	"github.com/redis/go-redis/v9"
+)
+
+// sharedKeyPrefix prefixes all keys the application stores in Redis
+const sharedKeyPrefix = "waste-tips:"
+
+// shared holds dependencies that must outlive a single invocation, such as in-memory stores and connection pools.
+// They are kept per configuration they depend on, so containers with a different configuration get their own.
+var shared struct {
+	redisClients  sharedMap[string, *redis.Client]
+	cacheStores   sharedMap[cacheKey, cache.Store]
+	rateLimits    sharedMap[rateLimitKey, ratelimit.Store]
+	deduplicators sharedMap[idempotencyKey, *idempotency.Deduplicator]
+	histories     sharedMap[historyKey, *history.SQLiteRepository]
+	geminiClients sharedMap[geminiKey, *geminiHTTPClient]
+	meters        sharedMap[meterKey, *meter.Meter]
+	catalogs      sharedMap[string, *catalog.FileCatalog]
+	keyStores     sharedMap[string, *auth.FileKeyStore]
+	experiments   sharedMap[string, *experiments.Experiment]
+}
+
+// sharedMap holds process-wide values by the configuration they were created from
+type sharedMap[K comparable, V any] struct {
+	mu     sync.Mutex
+	values map[K]V
+}
+
+// get returns the value of the key, creating it on first use.
+// Failures are not kept, so the next call tries again.
+func (m *sharedMap[K, V]) get(key K, create func() (V, error)) (V, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if value, exists := m.values[key]; exists {
+		return value, nil
+	}
+
+	value, err := create()
+	if err != nil {
+		return value, err
+	}
+	if m.values == nil {
+		m.values = make(map[K]V)
+	}
+	m.values[key] = value
+
+	return value, nil
+}
+
+// all returns the values created so far
+func (m *sharedMap[K, V]) all() []V {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := make([]V, 0, len(m.values))
+	for _, value := range m.values {
+		values = append(values, value)
+	}
+	return values
+}
+
+// cacheKey is the configuration of a classification cache store
+type cacheKey struct {
+	backend   string
+	size      int
+	redisAddr string
+}
+
+// rateLimitKey is the configuration of a token bucket store
+type rateLimitKey struct {
+	backend   string
+	redisAddr string
+}
+
+// idempotencyKey is the configuration of an idempotent request deduplicator
+type idempotencyKey struct {
+	backend   string
+	size      int
+	window    time.Duration
+	redisAddr string
+}
+
+// historyKey is the configuration of a history database
+type historyKey struct {
+	backend string
+	path    string
+}
+
+// geminiKey is the configuration of the Gemini HTTP client
+type geminiKey struct {
+	projectID    string
+	cassetteMode string
+	cassettePath string
+}
+
+// meterKey is the configuration of a meter provider
+type meterKey struct {
+	applicationName string
+	otlp            bool
+}
+
+// geminiHTTPClient is the HTTP client of the Gemini client with its credentials
+type geminiHTTPClient struct {
+	client      *http.Client
+	credentials *googleauth.Credentials
+	// recorder records the calls if the cassette mode is record
+	recorder *cassette.Recorder
+}
+
+// sharedRedisClient returns the process-wide Redis client of the address
+func sharedRedisClient(cfg *config.Config) *redis.Client {
+	client, _ := shared.redisClients.get(cfg.RedisAddr, func() (*redis.Client, error) {
+		return redis.NewClient(&redis.Options{Addr: cfg.RedisAddr}), nil
+	})
+
+	return client
+}
+
+// sharedCacheStore returns the process-wide classification cache store,
+// or nil if caching is disabled
+func sharedCacheStore(cfg *config.Config) cache.Store {
+	store, _ := shared.cacheStores.get(cacheKey{cfg.CacheBackend, cfg.CacheSize, cfg.RedisAddr}, func() (cache.Store, error) {
+		switch cfg.CacheBackend {
+		case "memory":
+			return cache.NewMemoryStore(cfg.CacheSize), nil
+		case "redis":
+			return cache.NewRedisStore(sharedRedisClient(cfg), sharedKeyPrefix+"cache:"), nil
+		}
+		return nil, nil
+	})
+
+	return store
+}
+
+// sharedRateLimitStore returns the process-wide token bucket store,
+// or nil if rate limiting is disabled
+func sharedRateLimitStore(cfg *config.Config) ratelimit.Store {
+	store, _ := shared.rateLimits.get(rateLimitKey{cfg.RateLimitBackend, cfg.RedisAddr}, func() (ratelimit.Store, error) {
+		switch cfg.RateLimitBackend {
+		case "memory":
+			return ratelimit.NewMemoryStore(), nil
+		case "redis":
+			return ratelimit.NewRedisStore(sharedRedisClient(cfg), sharedKeyPrefix+"ratelimit:"), nil
+		}
+		return nil, nil
+	})
+
+	return store
+}
+
+// sharedDeduplicator returns the process-wide idempotent request deduplicator,
+// or nil if idempotency keys are ignored
+func sharedDeduplicator(cfg *config.Config) *idempotency.Deduplicator {
+	key := idempotencyKey{cfg.IdempotencyBackend, cfg.IdempotencySize, cfg.IdempotencyWindow, cfg.RedisAddr}
+	deduplicator, _ := shared.deduplicators.get(key, func() (*idempotency.Deduplicator, error) {
+		switch cfg.IdempotencyBackend {
+		case "memory":
+			return idempotency.NewDeduplicator(cache.NewMemoryStore(cfg.IdempotencySize), cfg.IdempotencyWindow), nil
+		case "redis":
+			return idempotency.NewDeduplicator(cache.NewRedisStore(sharedRedisClient(cfg), sharedKeyPrefix+"idempotency:"), cfg.IdempotencyWindow), nil
+		}
+		return nil, nil
+	})
+
+	return deduplicator
+}
+
+// sharedHistoryRepository returns the process-wide history database,
+// or nil if the history is disabled
+func sharedHistoryRepository(cfg *config.Config) (*history.SQLiteRepository, error) {
+	return shared.histories.get(historyKey{cfg.HistoryBackend, cfg.HistoryDBPath}, func() (*history.SQLiteRepository, error) {
+		if cfg.HistoryBackend != "sqlite" {
+			return nil, nil
+		}
+		return history.NewSQLiteRepository(cfg.HistoryDBPath)
+	})
+}
+
+// sharedGeminiHTTPClient returns the process-wide HTTP client and credentials of the Gemini client.
+// The client propagates the trace context, and records to or replays from the cassette if configured.
+func sharedGeminiHTTPClient(cfg *config.Config) (*http.Client, *googleauth.Credentials, error) {
+	key := geminiKey{cfg.ProjectID, cfg.GeminiCassetteMode, cfg.GeminiCassettePath}
+	gemini, err := shared.geminiClients.get(key, func() (*geminiHTTPClient, error) {
+		return newGeminiHTTPClient(cfg)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return gemini.client, gemini.credentials, nil
+}
+
+// newGeminiHTTPClient creates the HTTP client of the Gemini client
+func newGeminiHTTPClient(cfg *config.Config) (*geminiHTTPClient, error) {
+	if cfg.GeminiCassetteMode == "replay" {
+		replayer, err := cassette.NewReplayer(cfg.GeminiCassettePath, cfg.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		// Static credentials keep the Gemini client from looking up default credentials
+		return &geminiHTTPClient{
+			client:      &http.Client{Transport: tracer.Transport(replayer)},
+			credentials: googleauth.NewCredentials(&googleauth.CredentialsOptions{TokenProvider: replayTokenProvider{}}),
+		}, nil
+	}
+
+	// The Gemini client does not authenticate a custom HTTP client, so it is authenticated here like the Gemini client would
+	creds, err := credentials.DetectDefault(&credentials.DetectOptions{
+		Scopes: []string{"https://www.googleapis.com/auth/cloud-platform"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error finding default credentials: %v", err)
+	}
+	quotaProjectID, err := creds.QuotaProjectID(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error getting quota project ID: %v", err)
+	}
+	client, err := httptransport.NewClient(&httptransport.Options{
+		Credentials: creds,
+		Headers:     http.Header{"X-Goog-User-Project": []string{quotaProjectID}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating HTTP client: %v", err)
+	}
+
+	gemini := &geminiHTTPClient{client: client, credentials: creds}
+	if cfg.GeminiCassetteMode == "record" {
+		gemini.recorder = cassette.NewRecorder(cfg.GeminiCassettePath, client.Transport, cfg.ProjectID)
+		client.Transport = gemini.recorder
+	}
+	client.Transport = tracer.Transport(client.Transport)
+
+	return gemini, nil
+}
+
+// sharedMeter returns the process-wide meter provider. Metrics accumulate across invocations,
+// so the provider is never closed. The global meter provider is the one created last.
+func sharedMeter(cfg *config.Config) (*meter.Meter, error) {
+	return shared.meters.get(meterKey{cfg.ApplicationName, cfg.MetricsOTLPEnabled}, func() (*meter.Meter, error) {
+		return meter.Init(context.Background(), cfg.ApplicationName, cfg.MetricsOTLPEnabled)
+	})
+}
+
+// sharedProductCatalog returns the product catalog of the file, which is read once per process
+func sharedProductCatalog(path string) (*catalog.FileCatalog, error) {
+	return shared.catalogs.get(path, func() (*catalog.FileCatalog, error) {
+		return catalog.NewFileCatalog(path)
+	})
+}
+
+// sharedKeyStore returns the API key store of the file, which is read once per process
+func sharedKeyStore(path string) (*auth.FileKeyStore, error) {
+	return shared.keyStores.get(path, func() (*auth.FileKeyStore, error) {
+		return auth.NewFileKeyStore(path)
+	})
+}
+
+// sharedExperiment returns the experiment of the file, which is read once per process,
+// or nil if no experiment is running
+func sharedExperiment(path string) (*experiments.Experiment, error) {
+	return shared.experiments.get(path, func() (*experiments.Experiment, error) {
+		return experiments.Load(path)
+	})
+}
+
+// MetricsHandler serves the application metrics in the Prometheus exposition format
+func MetricsHandler(cfg *config.Config) (http.Handler, error) {
+	m, err := sharedMeter(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize meter: %w", err)
+	}
+
+	return m.Handler(), nil
+}
+
+// Shutdown releases the process-wide dependencies when the process exits.
+// It writes the Gemini cassettes if calls are recorded.
+func Shutdown() error {
+	for _, gemini := range shared.geminiClients.all() {
+		if gemini.recorder == nil {
+			continue
+		}
+		if err := gemini.recorder.Close(); err != nil {
+			return fmt.Errorf("failed to write Gemini cassette: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// replayTokenProvider provides a token that is never sent anywhere
+type replayTokenProvider struct{}
+
+// Token returns a placeholder token
+func (replayTokenProvider) Token(context.Context) (*googleauth.Token, error) {
+	return &googleauth.Token{Value: cassette.Redacted, Type: "Bearer", Expiry: time.Now().Add(time.Hour)}, nil
+}
@@ -0,0 +1,92 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/config"
+)
+
+// replayConfig returns a configuration replaying an empty cassette written to a new directory
+func replayConfig(t *testing.T) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "gemini.cassette.json")
+	if err := os.WriteFile(path, []byte(`{"interactions":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return &config.Config{ProjectID: "test", GeminiCassetteMode: "replay", GeminiCassettePath: path}
+}
+
+func TestSharedGeminiHTTPClientIsKeptPerConfig(t *testing.T) {
+	first, second := replayConfig(t), replayConfig(t)
+
+	firstClient, _, err := sharedGeminiHTTPClient(first)
+	if err != nil {
+		t.Fatalf("sharedGeminiHTTPClient() error = %v", err)
+	}
+	secondClient, _, err := sharedGeminiHTTPClient(second)
+	if err != nil {
+		t.Fatalf("sharedGeminiHTTPClient() error = %v", err)
+	}
+	if firstClient == secondClient {
+		t.Error("configurations with different cassettes share a client")
+	}
+
+	again, _, err := sharedGeminiHTTPClient(&config.Config{ProjectID: "test", GeminiCassetteMode: "replay", GeminiCassettePath: first.GeminiCassettePath})
+	if err != nil {
+		t.Fatalf("sharedGeminiHTTPClient() error = %v", err)
+	}
+	if again != firstClient {
+		t.Error("an equal configuration did not reuse the client")
+	}
+}
+
+func TestSharedGeminiHTTPClientRetriesFailures(t *testing.T) {
+	cfg := &config.Config{ProjectID: "test", GeminiCassetteMode: "replay", GeminiCassettePath: filepath.Join(t.TempDir(), "gemini.cassette.json")}
+	if _, _, err := sharedGeminiHTTPClient(cfg); err == nil {
+		t.Fatal("sharedGeminiHTTPClient() of a missing cassette succeeded")
+	}
+
+	if err := os.WriteFile(cfg.GeminiCassettePath, []byte(`{"interactions":[]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sharedGeminiHTTPClient(cfg); err != nil {
+		t.Errorf("sharedGeminiHTTPClient() error = %v after the cassette was written", err)
+	}
+}
+
+func TestSharedCacheStoreIsKeptPerConfig(t *testing.T) {
+	small := sharedCacheStore(&config.Config{CacheBackend: "memory", CacheSize: 1})
+	large := sharedCacheStore(&config.Config{CacheBackend: "memory", CacheSize: 2})
+	if small == large {
+		t.Error("configurations with different cache sizes share a store")
+	}
+	if sharedCacheStore(&config.Config{CacheBackend: "memory", CacheSize: 1}) != small {
+		t.Error("an equal configuration did not reuse the store")
+	}
+	if sharedCacheStore(&config.Config{CacheBackend: "none"}) != nil {
+		t.Error("a disabled cache returned a store")
+	}
+}
+
+func TestSharedFilesAreReadOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(path, []byte(`[{"ean":"4006381333931","name":"Yogurt"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := sharedProductCatalog(path)
+	if err != nil {
+		t.Fatalf("sharedProductCatalog() error = %v", err)
+	}
+	// A second read would fail now, so an equal catalog means the file was not read again
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	second, err := sharedProductCatalog(path)
+	if err != nil || second != first {
+		t.Errorf("sharedProductCatalog() = %p, %v, want the catalog loaded before", second, err)
+	}
+}
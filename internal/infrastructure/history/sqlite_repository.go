@@ -0,0 +1,281 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+	_ "modernc.org/sqlite"
+)
+
+// schema creates the history and feedback tables of a new database, entries are listed per device
+// from newest to oldest. Only classifications of devices that sent an ID are stored, feedback
+// references them by their ID.
+const schema = `
+CREATE TABLE IF NOT EXISTS history (
+	id             TEXT PRIMARY KEY,
+	device_id      TEXT NOT NULL,
+	created_at     INTEGER NOT NULL,
+	postal_code    TEXT NOT NULL,
+	language       TEXT NOT NULL,
+	query          TEXT NOT NULL DEFAULT '',
+	image_count    INTEGER NOT NULL DEFAULT 0,
+	classification TEXT NOT NULL,
+	variant        TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS history_device_created ON history (device_id, created_at DESC);
+CREATE TABLE IF NOT EXISTS feedback (
+	classification_id TEXT PRIMARY KEY,
+	category          TEXT NOT NULL,
+	helpful           INTEGER NOT NULL,
+	component         TEXT NOT NULL DEFAULT '',
+	corrected_bin     TEXT NOT NULL DEFAULT '',
+	comment           TEXT NOT NULL DEFAULT '',
+	created_at        INTEGER NOT NULL,
+	variant           TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS feedback_category ON feedback (category);
+`
+
+// migrations upgrade databases created by earlier versions to the schema in order, the database's
+// user_version counts the migrations already applied. New databases start with all of them applied.
+var migrations = []string{
+	`ALTER TABLE history ADD COLUMN variant TEXT NOT NULL DEFAULT '';
+	ALTER TABLE feedback ADD COLUMN variant TEXT NOT NULL DEFAULT '';`,
+}
+
+// SQLiteRepository stores the classification history in an embedded SQLite database
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository opens the SQLite database at path and creates the schema if needed
+func NewSQLiteRepository(path string) (*SQLiteRepository, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("error opening history database: %v", err)
+	}
+	// SQLite allows a single writer, concurrent writes would fail with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteRepository{db: db}, nil
+}
+
+// migrate creates the schema of a new database or applies the migrations an existing database has not seen yet
+func migrate(db *sql.DB) error {
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'history'`).Scan(&tables); err != nil {
+		return fmt.Errorf("error reading history schema: %v", err)
+	}
+	if tables == 0 {
+		if _, err := db.Exec(schema + fmt.Sprintf(`PRAGMA user_version = %d;`, len(migrations))); err != nil {
+			return fmt.Errorf("error creating history schema: %v", err)
+		}
+		return nil
+	}
+
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("error reading history schema version: %v", err)
+	}
+
+	for ; version < len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("error migrating history schema: %v", err)
+		}
+		if _, err := tx.Exec(migrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error migrating history schema to version %d: %v", version+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error migrating history schema to version %d: %v", version+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error migrating history schema to version %d: %v", version+1, err)
+		}
+	}
+
+	return nil
+}
+
+// Save stores the history entry
+func (r *SQLiteRepository) Save(ctx context.Context, entry *models.HistoryEntry) error {
+	classification, err := json.Marshal(entry.Classification)
+	if err != nil {
+		return fmt.Errorf("error encoding classification: %v", err)
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO history (id, device_id, created_at, postal_code, language, query, image_count, classification, variant)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID, entry.DeviceID, entry.CreatedAt.UnixMilli(), entry.PostalCode, entry.Language, entry.Query, entry.ImageCount, string(classification), entry.Variant,
+	)
+	if err != nil {
+		return fmt.Errorf("error saving history entry: %v", err)
+	}
+
+	return nil
+}
+
+// Get returns the history entry with the given ID, or nil if it does not exist
+func (r *SQLiteRepository) Get(ctx context.Context, id string) (*models.HistoryEntry, error) {
+	entry := &models.HistoryEntry{ID: id}
+	var createdAt int64
+	var classification string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT device_id, created_at, postal_code, language, query, image_count, classification, variant
+		FROM history WHERE id = ?`,
+		id,
+	).Scan(&entry.DeviceID, &createdAt, &entry.PostalCode, &entry.Language, &entry.Query, &entry.ImageCount, &classification, &entry.Variant)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading history entry: %v", err)
+	}
+
+	entry.CreatedAt = time.UnixMilli(createdAt).UTC()
+	if err := json.Unmarshal([]byte(classification), &entry.Classification); err != nil {
+		return nil, fmt.Errorf("error decoding classification: %v", err)
+	}
+
+	return entry, nil
+}
+
+// List returns the most recent history entries of the device, newest first
+func (r *SQLiteRepository) List(ctx context.Context, deviceID string, limit int) ([]*models.HistoryEntry, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, created_at, postal_code, language, query, image_count, classification, variant
+		FROM history WHERE device_id = ? ORDER BY created_at DESC LIMIT ?`,
+		deviceID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error listing history: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []*models.HistoryEntry
+	for rows.Next() {
+		entry := &models.HistoryEntry{DeviceID: deviceID}
+		var createdAt int64
+		var classification string
+		if err := rows.Scan(&entry.ID, &createdAt, &entry.PostalCode, &entry.Language, &entry.Query, &entry.ImageCount, &classification, &entry.Variant); err != nil {
+			return nil, fmt.Errorf("error reading history entry: %v", err)
+		}
+		entry.CreatedAt = time.UnixMilli(createdAt).UTC()
+		if err := json.Unmarshal([]byte(classification), &entry.Classification); err != nil {
+			return nil, fmt.Errorf("error decoding classification: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing history: %v", err)
+	}
+
+	return entries, nil
+}
+
+// Delete removes all history entries of the device and the feedback on them, which may contain comments
+func (r *SQLiteRepository) Delete(ctx context.Context, deviceID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error deleting history: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM feedback WHERE classification_id IN (SELECT id FROM history WHERE device_id = ?)`, deviceID); err != nil {
+		return fmt.Errorf("error deleting feedback: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM history WHERE device_id = ?`, deviceID); err != nil {
+		return fmt.Errorf("error deleting history: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error deleting history: %v", err)
+	}
+
+	return nil
+}
+
+// SaveFeedback stores the feedback, replacing earlier feedback on the same classification
+func (r *SQLiteRepository) SaveFeedback(ctx context.Context, feedback *models.Feedback) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO feedback (classification_id, category, helpful, component, corrected_bin, comment, created_at, variant)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		feedback.ClassificationID, feedback.Category, feedback.Helpful, feedback.Component, feedback.CorrectedBin, feedback.Comment, feedback.CreatedAt.UnixMilli(), feedback.Variant,
+	)
+	if err != nil {
+		return fmt.Errorf("error saving feedback: %v", err)
+	}
+
+	return nil
+}
+
+// FeedbackAccuracy aggregates the feedback per item category and experiment variant, ordered by the amount of feedback
+func (r *SQLiteRepository) FeedbackAccuracy(ctx context.Context) ([]*models.CategoryAccuracy, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT category, variant, COUNT(*), SUM(helpful) FROM feedback
+		GROUP BY category, variant ORDER BY COUNT(*) DESC, category, variant`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating feedback: %v", err)
+	}
+	defer rows.Close()
+
+	var accuracy []*models.CategoryAccuracy
+	groups := map[[2]string]*models.CategoryAccuracy{}
+	for rows.Next() {
+		group := &models.CategoryAccuracy{}
+		if err := rows.Scan(&group.Category, &group.Variant, &group.Total, &group.Helpful); err != nil {
+			return nil, fmt.Errorf("error reading feedback aggregate: %v", err)
+		}
+		group.Accuracy = float64(group.Helpful) / float64(group.Total)
+		accuracy = append(accuracy, group)
+		groups[[2]string{group.Category, group.Variant}] = group
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error aggregating feedback: %v", err)
+	}
+
+	corrections, err := r.db.QueryContext(ctx,
+		`SELECT category, variant, corrected_bin, COUNT(*) FROM feedback
+		WHERE corrected_bin != '' GROUP BY category, variant, corrected_bin`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error aggregating corrections: %v", err)
+	}
+	defer corrections.Close()
+
+	for corrections.Next() {
+		var category, variant, bin string
+		var count int
+		if err := corrections.Scan(&category, &variant, &bin, &count); err != nil {
+			return nil, fmt.Errorf("error reading correction aggregate: %v", err)
+		}
+		if group, exists := groups[[2]string{category, variant}]; exists {
+			if group.Corrections == nil {
+				group.Corrections = map[string]int{}
+			}
+			group.Corrections[bin] = count
+		}
+	}
+	if err := corrections.Err(); err != nil {
+		return nil, fmt.Errorf("error aggregating corrections: %v", err)
+	}
+
+	return accuracy, nil
+}
+
+// Close closes the database
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
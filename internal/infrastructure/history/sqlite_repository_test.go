@@ -0,0 +1,213 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/domain/models"
+)
+
+// newTestRepository opens a repository on an in-memory database
+func newTestRepository(t *testing.T) *SQLiteRepository {
+	t.Helper()
+	repository, err := NewSQLiteRepository(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repository.Close() })
+	return repository
+}
+
+// testEntry returns an entry of the device created at the given minute
+func testEntry(id, deviceID string, minute int) *models.HistoryEntry {
+	return &models.HistoryEntry{
+		ID:         id,
+		DeviceID:   deviceID,
+		CreatedAt:  time.Date(2026, 5, 4, 12, minute, 0, 0, time.UTC),
+		PostalCode: "10115",
+		Language:   "de",
+		Query:      "bottle",
+		Classification: &models.Classification{
+			Item:          "glass bottle",
+			Category:      "glass",
+			PromptVersion: "classification@v1",
+		},
+		Variant: "prompt-v2/treatment",
+	}
+}
+
+func TestSQLiteRepositorySavesEntries(t *testing.T) {
+	ctx := context.Background()
+	repository := newTestRepository(t)
+
+	entry := testEntry("entry-1", "device-a", 0)
+	if err := repository.Save(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repository.Get(ctx, "entry-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || got.DeviceID != entry.DeviceID || !got.CreatedAt.Equal(entry.CreatedAt) || got.Query != entry.Query ||
+		got.Variant != entry.Variant || got.Classification.Category != "glass" || got.Classification.PromptVersion != "classification@v1" {
+		t.Errorf("Get() = %+v, want %+v", got, entry)
+	}
+
+	if got, err := repository.Get(ctx, "unknown"); got != nil || err != nil {
+		t.Errorf("Get() of an unknown entry = %+v, %v, want nil", got, err)
+	}
+}
+
+func TestSQLiteRepositoryListsNewestFirst(t *testing.T) {
+	ctx := context.Background()
+	repository := newTestRepository(t)
+
+	for _, entry := range []*models.HistoryEntry{
+		testEntry("entry-1", "device-a", 0),
+		testEntry("entry-3", "device-a", 2),
+		testEntry("entry-2", "device-a", 1),
+		testEntry("other", "device-b", 3),
+	} {
+		if err := repository.Save(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := repository.List(ctx, "device-a", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	if len(ids) != 2 || ids[0] != "entry-3" || ids[1] != "entry-2" {
+		t.Errorf("List() = %q, want the two newest entries of the device", ids)
+	}
+}
+
+func TestSQLiteRepositoryDeletesEntriesAndFeedback(t *testing.T) {
+	ctx := context.Background()
+	repository := newTestRepository(t)
+
+	for _, entry := range []*models.HistoryEntry{testEntry("entry-1", "device-a", 0), testEntry("other", "device-b", 1)} {
+		if err := repository.Save(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+		if err := repository.SaveFeedback(ctx, &models.Feedback{
+			ClassificationID: entry.ID,
+			Category:         "glass",
+			Comment:          "wrong bin",
+			CreatedAt:        entry.CreatedAt,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repository.Delete(ctx, "device-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, err := repository.List(ctx, "device-a", 10); err != nil || len(entries) != 0 {
+		t.Errorf("List() after Delete() = %d entries, %v, want none", len(entries), err)
+	}
+	if entries, err := repository.List(ctx, "device-b", 10); err != nil || len(entries) != 1 {
+		t.Errorf("List() of another device = %d entries, %v, want it kept", len(entries), err)
+	}
+
+	var ids []string
+	rows, err := repository.db.QueryContext(ctx, `SELECT classification_id FROM feedback`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) != 1 || ids[0] != "other" {
+		t.Errorf("feedback after Delete() = %q, want only the feedback of the other device", ids)
+	}
+}
+
+func TestSQLiteRepositoryMigratesEarlierSchema(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "history.db")
+
+	// The schema before experiment variants were stored
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`
+CREATE TABLE history (
+	id             TEXT PRIMARY KEY,
+	device_id      TEXT NOT NULL,
+	created_at     INTEGER NOT NULL,
+	postal_code    TEXT NOT NULL,
+	language       TEXT NOT NULL,
+	query          TEXT NOT NULL DEFAULT '',
+	image_count    INTEGER NOT NULL DEFAULT 0,
+	classification TEXT NOT NULL
+);
+CREATE TABLE feedback (
+	classification_id TEXT PRIMARY KEY,
+	category          TEXT NOT NULL,
+	helpful           INTEGER NOT NULL,
+	component         TEXT NOT NULL DEFAULT '',
+	corrected_bin     TEXT NOT NULL DEFAULT '',
+	comment           TEXT NOT NULL DEFAULT '',
+	created_at        INTEGER NOT NULL
+);
+INSERT INTO history (id, device_id, created_at, postal_code, language, classification)
+VALUES ('old', 'device-a', 0, '10115', 'de', '{"category":"glass"}');
+`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repository, err := NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repository.Close() })
+
+	var version int
+	if err := repository.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil || version != len(migrations) {
+		t.Errorf("user_version = %d, %v, want %d", version, err, len(migrations))
+	}
+	if entry, err := repository.Get(ctx, "old"); err != nil || entry == nil || entry.Variant != "" {
+		t.Errorf("Get() of an entry saved before the migration = %+v, %v", entry, err)
+	}
+	if err := repository.Save(ctx, testEntry("new", "device-a", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if entry, err := repository.Get(ctx, "new"); err != nil || entry == nil || entry.Variant != "prompt-v2/treatment" {
+		t.Errorf("Get() of an entry saved after the migration = %+v, %v", entry, err)
+	}
+
+	// Reopening the migrated database applies no migration twice
+	repository.Close()
+	reopened, err := NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatalf("reopening the migrated database: %v", err)
+	}
+	reopened.Close()
+}
+
+func TestSQLiteRepositoryCreatesCurrentSchema(t *testing.T) {
+	repository := newTestRepository(t)
+
+	var version int
+	if err := repository.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil || version != len(migrations) {
+		t.Errorf("user_version of a new database = %d, %v, want %d", version, err, len(migrations))
+	}
+}
@@ -0,0 +1,127 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/cache"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrKeyReused is returned when an idempotency key is reused for a different request
+var ErrKeyReused = errors.New("idempotency key reused for a different request")
+
+// Response is a completed response stored for replay
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       []byte      `json:"body,omitempty"`
+	// Fingerprint identifies the request the response was produced for
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Deduplicator stores completed responses by idempotency key and collapses concurrent duplicates
+// into a single execution. It must be shared across requests for the collapsing to work.
+type Deduplicator struct {
+	store  cache.Store
+	window time.Duration
+	group  singleflight.Group
+}
+
+// NewDeduplicator creates a new deduplicator keeping completed responses for the window
+func NewDeduplicator(store cache.Store, window time.Duration) *Deduplicator {
+	return &Deduplicator{
+		store:  store,
+		window: window,
+	}
+}
+
+// Do returns the stored response for the key, waits for the in-flight request with the same key,
+// or executes fn and stores its response if it is worth replaying.
+// fn runs on a context that is not canceled with ctx, as requests waiting for it would fail
+// if the client that started it disconnected.
+// The returned flag reports whether the response was produced by an earlier or concurrent request.
+// If that request had another fingerprint, ErrKeyReused is returned instead of its response.
+// If the response could not be stored, it is returned together with the error.
+func (d *Deduplicator) Do(ctx context.Context, key, fingerprint string, fn func(ctx context.Context) *Response) (*Response, bool, error) {
+	if response, found, err := d.load(ctx, key); err != nil {
+		return nil, false, err
+	} else if found {
+		return matchFingerprint(response, fingerprint)
+	}
+
+	executed := false
+	result, err, _ := d.group.Do(key, func() (interface{}, error) {
+		sharedCtx := context.WithoutCancel(ctx)
+
+		// A request with the same key may have completed between the lookup and this call
+		if response, found, err := d.load(sharedCtx, key); err != nil || found {
+			return response, err
+		}
+
+		executed = true
+		response := fn(sharedCtx)
+		response.Fingerprint = fingerprint
+		if !isReplayable(response) {
+			return response, nil
+		}
+		return response, d.save(sharedCtx, key, response)
+	})
+
+	response, _ := result.(*Response)
+	if !executed && response != nil {
+		return matchFingerprint(response, fingerprint)
+	}
+	return response, !executed, err
+}
+
+// matchFingerprint returns the response of an earlier request if it had the same fingerprint
+func matchFingerprint(response *Response, fingerprint string) (*Response, bool, error) {
+	if response.Fingerprint != fingerprint {
+		return nil, true, ErrKeyReused
+	}
+
+	return response, true, nil
+}
+
+// load returns the stored response for the key
+func (d *Deduplicator) load(ctx context.Context, key string) (*Response, bool, error) {
+	value, found, err := d.store.Get(ctx, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load idempotent response: %v", err)
+	}
+	if !found {
+		return nil, false, nil
+	}
+
+	var response Response
+	if err := json.Unmarshal([]byte(value), &response); err != nil {
+		return nil, false, fmt.Errorf("failed to decode idempotent response: %v", err)
+	}
+
+	return &response, true, nil
+}
+
+// save stores the response for the key for the configured window
+func (d *Deduplicator) save(ctx context.Context, key string, response *Response) error {
+	value, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotent response: %v", err)
+	}
+
+	if err := d.store.Set(ctx, key, string(value), d.window); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %v", err)
+	}
+
+	return nil
+}
+
+// isReplayable reports whether the response is final. Rate limited requests and server errors
+// are not stored, so a retry with the same key gets another chance.
+func isReplayable(response *Response) bool {
+	return response.StatusCode != http.StatusTooManyRequests && response.StatusCode < http.StatusInternalServerError
+}
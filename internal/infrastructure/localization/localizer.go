@@ -1,12 +1,25 @@
 package localization
 
+import "strings"
+
 // ErrorMessages contains localized error messages
 type ErrorMessages struct {
-	InvalidPostalCode string `json:"invalid_postal_code"`
-	InvalidImage      string `json:"invalid_image"`
-	RecaptchaFailed   string `json:"recaptcha_failed"`
-	ProcessingError   string `json:"processing_error"`
-	MissingFields     string `json:"missing_fields"`
+	InvalidPostalCode      string `json:"invalid_postal_code"`
+	InvalidImage           string `json:"invalid_image"`
+	RecaptchaFailed        string `json:"recaptcha_failed"`
+	ProcessingError        string `json:"processing_error"`
+	MissingFields          string `json:"missing_fields"`
+	TooManyImages          string `json:"too_many_images"`
+	ImagesTooLarge         string `json:"images_too_large"`
+	InvalidQuery           string `json:"invalid_query"`
+	RateLimited            string `json:"rate_limited"`
+	InvalidAPIKey          string `json:"invalid_api_key"`
+	OriginNotAllowed       string `json:"origin_not_allowed"`
+	CaptchaFailed          string `json:"captcha_failed"`
+	InvalidDeviceID        string `json:"invalid_device_id"`
+	InvalidFeedback        string `json:"invalid_feedback"`
+	ClassificationNotFound string `json:"classification_not_found"`
+	IdempotencyKeyReused   string `json:"idempotency_key_reused"`
 }
 
 // Localizer handles localization of messages
@@ -27,179 +40,454 @@ func NewLocalizer() *Localizer {
 
 	errorMessages := map[string]ErrorMessages{
 		"en": {
-			InvalidPostalCode: "Invalid German postal code",
-			InvalidImage:      "Invalid image file",
-			RecaptchaFailed:   "reCAPTCHA verification failed",
-			ProcessingError:   "Error processing your request",
-			MissingFields:     "Missing required fields",
+			InvalidPostalCode:      "Invalid German postal code",
+			InvalidImage:           "Invalid image file",
+			RecaptchaFailed:        "reCAPTCHA verification failed",
+			ProcessingError:        "Error processing your request",
+			MissingFields:          "Missing required fields",
+			TooManyImages:          "Too many images",
+			ImagesTooLarge:         "Images are too large",
+			InvalidQuery:           "Invalid item description",
+			RateLimited:            "Too many requests, please try again later",
+			InvalidAPIKey:          "Invalid API key",
+			OriginNotAllowed:       "Origin not allowed",
+			CaptchaFailed:          "Captcha verification failed",
+			InvalidDeviceID:        "Invalid device ID",
+			InvalidFeedback:        "Invalid feedback",
+			ClassificationNotFound: "Classification not found",
+			IdempotencyKeyReused:   "The idempotency key was already used for a different request",
 		},
 		"de": {
-			InvalidPostalCode: "Ungültige deutsche Postleitzahl",
-			InvalidImage:      "Ungültige Bilddatei",
-			RecaptchaFailed:   "reCAPTCHA-Verifizierung fehlgeschlagen",
-			ProcessingError:   "Fehler bei der Verarbeitung Ihrer Anfrage",
-			MissingFields:     "Pflichtfelder fehlen",
+			InvalidPostalCode:      "Ungültige deutsche Postleitzahl",
+			InvalidImage:           "Ungültige Bilddatei",
+			RecaptchaFailed:        "reCAPTCHA-Verifizierung fehlgeschlagen",
+			ProcessingError:        "Fehler bei der Verarbeitung Ihrer Anfrage",
+			MissingFields:          "Pflichtfelder fehlen",
+			TooManyImages:          "Zu viele Bilder",
+			ImagesTooLarge:         "Bilder sind zu groß",
+			InvalidQuery:           "Ungültige Artikelbeschreibung",
+			RateLimited:            "Zu viele Anfragen, bitte versuchen Sie es später erneut",
+			InvalidAPIKey:          "Ungültiger API-Schlüssel",
+			OriginNotAllowed:       "Herkunft nicht erlaubt",
+			CaptchaFailed:          "Captcha-Verifizierung fehlgeschlagen",
+			InvalidDeviceID:        "Ungültige Geräte-ID",
+			InvalidFeedback:        "Ungültiges Feedback",
+			ClassificationNotFound: "Klassifizierung nicht gefunden",
+			IdempotencyKeyReused:   "Der Idempotency-Key wurde bereits für eine andere Anfrage verwendet",
 		},
 		"ru": {
-			InvalidPostalCode: "Неверный немецкий почтовый индекс",
-			InvalidImage:      "Неверный файл изображения",
-			RecaptchaFailed:   "Проверка reCAPTCHA не удалась",
-			ProcessingError:   "Ошибка обработки вашего запроса",
-			MissingFields:     "Отсутствуют обязательные поля",
+			InvalidPostalCode:      "Неверный немецкий почтовый индекс",
+			InvalidImage:           "Неверный файл изображения",
+			RecaptchaFailed:        "Проверка reCAPTCHA не удалась",
+			ProcessingError:        "Ошибка обработки вашего запроса",
+			MissingFields:          "Отсутствуют обязательные поля",
+			TooManyImages:          "Слишком много изображений",
+			ImagesTooLarge:         "Изображения слишком большие",
+			InvalidQuery:           "Неверное описание предмета",
+			RateLimited:            "Слишком много запросов, пожалуйста, повторите попытку позже",
+			InvalidAPIKey:          "Неверный API-ключ",
+			OriginNotAllowed:       "Источник запроса не разрешён",
+			CaptchaFailed:          "Проверка капчи не удалась",
+			InvalidDeviceID:        "Недействительный идентификатор устройства",
+			InvalidFeedback:        "Недопустимый отзыв",
+			ClassificationNotFound: "Классификация не найдена",
+			IdempotencyKeyReused:   "Ключ идемпотентности уже использован для другого запроса",
 		},
 		"tr": {
-			InvalidPostalCode: "Geçersiz Alman posta kodu",
-			InvalidImage:      "Geçersiz resim dosyası",
-			RecaptchaFailed:   "reCAPTCHA doğrulaması başarısız",
-			ProcessingError:   "İsteğinizi işleme hatası",
-			MissingFields:     "Gerekli alanlar eksik",
+			InvalidPostalCode:      "Geçersiz Alman posta kodu",
+			InvalidImage:           "Geçersiz resim dosyası",
+			RecaptchaFailed:        "reCAPTCHA doğrulaması başarısız",
+			ProcessingError:        "İsteğinizi işleme hatası",
+			MissingFields:          "Gerekli alanlar eksik",
+			TooManyImages:          "Çok fazla resim",
+			ImagesTooLarge:         "Resimler çok büyük",
+			InvalidQuery:           "Geçersiz ürün açıklaması",
+			RateLimited:            "Çok fazla istek, lütfen daha sonra tekrar deneyin",
+			InvalidAPIKey:          "Geçersiz API anahtarı",
+			OriginNotAllowed:       "Kaynağa izin verilmiyor",
+			CaptchaFailed:          "Captcha doğrulaması başarısız",
+			InvalidDeviceID:        "Geçersiz cihaz kimliği",
+			InvalidFeedback:        "Geçersiz geri bildirim",
+			ClassificationNotFound: "Sınıflandırma bulunamadı",
+			IdempotencyKeyReused:   "Idempotency anahtarı başka bir istek için zaten kullanıldı",
 		},
 		"pl": {
-			InvalidPostalCode: "Nieprawidłowy niemiecki kod pocztowy",
-			InvalidImage:      "Nieprawidłowy plik obrazu",
-			RecaptchaFailed:   "Weryfikacja reCAPTCHA nie powiodła się",
-			ProcessingError:   "Błąd przetwarzania Twojego żądania",
-			MissingFields:     "Brakuje wymaganych pól",
+			InvalidPostalCode:      "Nieprawidłowy niemiecki kod pocztowy",
+			InvalidImage:           "Nieprawidłowy plik obrazu",
+			RecaptchaFailed:        "Weryfikacja reCAPTCHA nie powiodła się",
+			ProcessingError:        "Błąd przetwarzania Twojego żądania",
+			MissingFields:          "Brakuje wymaganych pól",
+			TooManyImages:          "Zbyt wiele obrazów",
+			ImagesTooLarge:         "Obrazy są zbyt duże",
+			InvalidQuery:           "Nieprawidłowy opis przedmiotu",
+			RateLimited:            "Zbyt wiele żądań, spróbuj ponownie później",
+			InvalidAPIKey:          "Nieprawidłowy klucz API",
+			OriginNotAllowed:       "Niedozwolone źródło żądania",
+			CaptchaFailed:          "Weryfikacja captcha nie powiodła się",
+			InvalidDeviceID:        "Nieprawidłowy identyfikator urządzenia",
+			InvalidFeedback:        "Nieprawidłowa opinia",
+			ClassificationNotFound: "Nie znaleziono klasyfikacji",
+			IdempotencyKeyReused:   "Klucz idempotencji został już użyty dla innego żądania",
 		},
 		"ar": {
-			InvalidPostalCode: "رمز بريدي ألماني غير صالح",
-			InvalidImage:      "ملف صورة غير صالح",
-			RecaptchaFailed:   "فشل التحقق من reCAPTCHA",
-			ProcessingError:   "خطأ في معالجة طلبك",
-			MissingFields:     "حقول مطلوبة مفقودة",
+			InvalidPostalCode:      "رمز بريدي ألماني غير صالح",
+			InvalidImage:           "ملف صورة غير صالح",
+			RecaptchaFailed:        "فشل التحقق من reCAPTCHA",
+			ProcessingError:        "خطأ في معالجة طلبك",
+			MissingFields:          "حقول مطلوبة مفقودة",
+			TooManyImages:          "عدد الصور كبير جدًا",
+			ImagesTooLarge:         "الصور كبيرة جدًا",
+			InvalidQuery:           "وصف العنصر غير صالح",
+			RateLimited:            "طلبات كثيرة جدًا، يرجى المحاولة لاحقًا",
+			InvalidAPIKey:          "مفتاح API غير صالح",
+			OriginNotAllowed:       "المصدر غير مسموح به",
+			CaptchaFailed:          "فشل التحقق من رمز captcha",
+			InvalidDeviceID:        "معرّف الجهاز غير صالح",
+			InvalidFeedback:        "ملاحظات غير صالحة",
+			ClassificationNotFound: "لم يتم العثور على التصنيف",
+			IdempotencyKeyReused:   "تم استخدام مفتاح عدم التكرار بالفعل لطلب آخر",
 		},
 		"ku": {
-			InvalidPostalCode: "Koda postê ya Almanî ya nederust",
-			InvalidImage:      "Pelê wêneyê nederust",
-			RecaptchaFailed:   "Piştrastkirina reCAPTCHA têk çû",
-			ProcessingError:   "Di pêvajoya daxwaza te de çewtî",
-			MissingFields:     "Zeviyên pêwîst kêm in",
+			InvalidPostalCode:      "Koda postê ya Almanî ya nederust",
+			InvalidImage:           "Pelê wêneyê nederust",
+			RecaptchaFailed:        "Piştrastkirina reCAPTCHA têk çû",
+			ProcessingError:        "Di pêvajoya daxwaza te de çewtî",
+			MissingFields:          "Zeviyên pêwîst kêm in",
+			TooManyImages:          "Wêne pir zêde ne",
+			ImagesTooLarge:         "Wêne pir mezin in",
+			InvalidQuery:           "Danasîna tiştê nederust",
+			RateLimited:            "Gelek daxwaz, ji kerema xwe paşê dîsa biceribîne",
+			InvalidAPIKey:          "Mifteya API ya nederust",
+			OriginNotAllowed:       "Çavkanî destûr nayê dayîn",
+			CaptchaFailed:          "Piştrastkirina captcha têk çû",
+			InvalidDeviceID:        "Nasnameya amûrê nederbasdar e",
+			InvalidFeedback:        "Nêrîna nederbasdar",
+			ClassificationNotFound: "Dabeşkirin nehat dîtin",
+			IdempotencyKeyReused:   "Mifteya idempotency berê ji bo daxwazek din hatiye bikaranîn",
 		},
 		"it": {
-			InvalidPostalCode: "Codice postale tedesco non valido",
-			InvalidImage:      "File immagine non valido",
-			RecaptchaFailed:   "Verifica reCAPTCHA fallita",
-			ProcessingError:   "Errore nell'elaborazione della richiesta",
-			MissingFields:     "Campi obbligatori mancanti",
+			InvalidPostalCode:      "Codice postale tedesco non valido",
+			InvalidImage:           "File immagine non valido",
+			RecaptchaFailed:        "Verifica reCAPTCHA fallita",
+			ProcessingError:        "Errore nell'elaborazione della richiesta",
+			MissingFields:          "Campi obbligatori mancanti",
+			TooManyImages:          "Troppe immagini",
+			ImagesTooLarge:         "Le immagini sono troppo grandi",
+			InvalidQuery:           "Descrizione dell'oggetto non valida",
+			RateLimited:            "Troppe richieste, riprova più tardi",
+			InvalidAPIKey:          "Chiave API non valida",
+			OriginNotAllowed:       "Origine non consentita",
+			CaptchaFailed:          "Verifica captcha fallita",
+			InvalidDeviceID:        "ID dispositivo non valido",
+			InvalidFeedback:        "Feedback non valido",
+			ClassificationNotFound: "Classificazione non trovata",
+			IdempotencyKeyReused:   "La chiave di idempotenza è già stata usata per una richiesta diversa",
 		},
 		"bs": {
-			InvalidPostalCode: "Neispravan njemački poštanski broj",
-			InvalidImage:      "Neispravna datoteka slike",
-			RecaptchaFailed:   "reCAPTCHA provjera neuspješna",
-			ProcessingError:   "Greška pri obradi zahtjeva",
-			MissingFields:     "Nedostaju obavezna polja",
+			InvalidPostalCode:      "Neispravan njemački poštanski broj",
+			InvalidImage:           "Neispravna datoteka slike",
+			RecaptchaFailed:        "reCAPTCHA provjera neuspješna",
+			ProcessingError:        "Greška pri obradi zahtjeva",
+			MissingFields:          "Nedostaju obavezna polja",
+			TooManyImages:          "Previše slika",
+			ImagesTooLarge:         "Slike su prevelike",
+			InvalidQuery:           "Neispravan opis predmeta",
+			RateLimited:            "Previše zahtjeva, pokušajte ponovo kasnije",
+			InvalidAPIKey:          "Neispravan API ključ",
+			OriginNotAllowed:       "Porijeklo nije dozvoljeno",
+			CaptchaFailed:          "Captcha provjera neuspješna",
+			InvalidDeviceID:        "Nevažeći ID uređaja",
+			InvalidFeedback:        "Nevažeća povratna informacija",
+			ClassificationNotFound: "Klasifikacija nije pronađena",
+			IdempotencyKeyReused:   "Ključ idempotentnosti je već korišten za drugi zahtjev",
 		},
 		"hr": {
-			InvalidPostalCode: "Neispravan njemački poštanski broj",
-			InvalidImage:      "Neispravna datoteka slike",
-			RecaptchaFailed:   "reCAPTCHA provjera neuspješna",
-			ProcessingError:   "Greška pri obradi zahtjeva",
-			MissingFields:     "Nedostaju obavezna polja",
+			InvalidPostalCode:      "Neispravan njemački poštanski broj",
+			InvalidImage:           "Neispravna datoteka slike",
+			RecaptchaFailed:        "reCAPTCHA provjera neuspješna",
+			ProcessingError:        "Greška pri obradi zahtjeva",
+			MissingFields:          "Nedostaju obavezna polja",
+			TooManyImages:          "Previše slika",
+			ImagesTooLarge:         "Slike su prevelike",
+			InvalidQuery:           "Neispravan opis predmeta",
+			RateLimited:            "Previše zahtjeva, pokušajte ponovno kasnije",
+			InvalidAPIKey:          "Neispravan API ključ",
+			OriginNotAllowed:       "Podrijetlo nije dopušteno",
+			CaptchaFailed:          "Captcha provjera neuspješna",
+			InvalidDeviceID:        "Nevažeći ID uređaja",
+			InvalidFeedback:        "Nevažeća povratna informacija",
+			ClassificationNotFound: "Klasifikacija nije pronađena",
+			IdempotencyKeyReused:   "Ključ idempotentnosti već je korišten za drugi zahtjev",
 		},
 		"sr": {
-			InvalidPostalCode: "Неисправан немачки поштански број",
-			InvalidImage:      "Неисправна датотека слике",
-			RecaptchaFailed:   "reCAPTCHA провера неуспешна",
-			ProcessingError:   "Грешка при обради захтева",
-			MissingFields:     "Недостају обавезна поља",
+			InvalidPostalCode:      "Неисправан немачки поштански број",
+			InvalidImage:           "Неисправна датотека слике",
+			RecaptchaFailed:        "reCAPTCHA провера неуспешна",
+			ProcessingError:        "Грешка при обради захтева",
+			MissingFields:          "Недостају обавезна поља",
+			TooManyImages:          "Превише слика",
+			ImagesTooLarge:         "Слике су превелике",
+			InvalidQuery:           "Неисправан опис предмета",
+			RateLimited:            "Превише захтева, покушајте поново касније",
+			InvalidAPIKey:          "Неисправан API кључ",
+			OriginNotAllowed:       "Порекло није дозвољено",
+			CaptchaFailed:          "Captcha провера неуспешна",
+			InvalidDeviceID:        "Неважећи ИД уређаја",
+			InvalidFeedback:        "Неважећа повратна информација",
+			ClassificationNotFound: "Класификација није пронађена",
+			IdempotencyKeyReused:   "Кључ идемпотентности је већ коришћен за други захтев",
 		},
 		"ro": {
-			InvalidPostalCode: "Cod poștal german invalid",
-			InvalidImage:      "Fișier imagine invalid",
-			RecaptchaFailed:   "Verificarea reCAPTCHA a eșuat",
-			ProcessingError:   "Eroare la procesarea cererii",
-			MissingFields:     "Câmpuri obligatorii lipsă",
+			InvalidPostalCode:      "Cod poștal german invalid",
+			InvalidImage:           "Fișier imagine invalid",
+			RecaptchaFailed:        "Verificarea reCAPTCHA a eșuat",
+			ProcessingError:        "Eroare la procesarea cererii",
+			MissingFields:          "Câmpuri obligatorii lipsă",
+			TooManyImages:          "Prea multe imagini",
+			ImagesTooLarge:         "Imaginile sunt prea mari",
+			InvalidQuery:           "Descriere invalidă a obiectului",
+			RateLimited:            "Prea multe cereri, vă rugăm să încercați mai târziu",
+			InvalidAPIKey:          "Cheie API invalidă",
+			OriginNotAllowed:       "Origine nepermisă",
+			CaptchaFailed:          "Verificarea captcha a eșuat",
+			InvalidDeviceID:        "ID de dispozitiv nevalid",
+			InvalidFeedback:        "Feedback nevalid",
+			ClassificationNotFound: "Clasificarea nu a fost găsită",
+			IdempotencyKeyReused:   "Cheia de idempotență a fost deja folosită pentru o altă cerere",
 		},
 		"el": {
-			InvalidPostalCode: "Μη έγκυρος γερμανικός ταχυδρομικός κώδικας",
-			InvalidImage:      "Μη έγκυρο αρχείο εικόνας",
-			RecaptchaFailed:   "Η επαλήθευση reCAPTCHA απέτυχε",
-			ProcessingError:   "Σφάλμα επεξεργασίας του αιτήματός σας",
-			MissingFields:     "Λείπουν υποχρεωτικά πεδία",
+			InvalidPostalCode:      "Μη έγκυρος γερμανικός ταχυδρομικός κώδικας",
+			InvalidImage:           "Μη έγκυρο αρχείο εικόνας",
+			RecaptchaFailed:        "Η επαλήθευση reCAPTCHA απέτυχε",
+			ProcessingError:        "Σφάλμα επεξεργασίας του αιτήματός σας",
+			MissingFields:          "Λείπουν υποχρεωτικά πεδία",
+			TooManyImages:          "Πάρα πολλές εικόνες",
+			ImagesTooLarge:         "Οι εικόνες είναι πολύ μεγάλες",
+			InvalidQuery:           "Μη έγκυρη περιγραφή αντικειμένου",
+			RateLimited:            "Πάρα πολλά αιτήματα, δοκιμάστε ξανά αργότερα",
+			InvalidAPIKey:          "Μη έγκυρο κλειδί API",
+			OriginNotAllowed:       "Η προέλευση δεν επιτρέπεται",
+			CaptchaFailed:          "Η επαλήθευση captcha απέτυχε",
+			InvalidDeviceID:        "Μη έγκυρο αναγνωριστικό συσκευής",
+			InvalidFeedback:        "Μη έγκυρα σχόλια",
+			ClassificationNotFound: "Η ταξινόμηση δεν βρέθηκε",
+			IdempotencyKeyReused:   "Το κλειδί idempotency έχει ήδη χρησιμοποιηθεί για άλλο αίτημα",
 		},
 		"es": {
-			InvalidPostalCode: "Código postal alemán inválido",
-			InvalidImage:      "Archivo de imagen inválido",
-			RecaptchaFailed:   "Verificación reCAPTCHA fallida",
-			ProcessingError:   "Error procesando su solicitud",
-			MissingFields:     "Faltan campos requeridos",
+			InvalidPostalCode:      "Código postal alemán inválido",
+			InvalidImage:           "Archivo de imagen inválido",
+			RecaptchaFailed:        "Verificación reCAPTCHA fallida",
+			ProcessingError:        "Error procesando su solicitud",
+			MissingFields:          "Faltan campos requeridos",
+			TooManyImages:          "Demasiadas imágenes",
+			ImagesTooLarge:         "Las imágenes son demasiado grandes",
+			InvalidQuery:           "Descripción del artículo inválida",
+			RateLimited:            "Demasiadas solicitudes, inténtelo de nuevo más tarde",
+			InvalidAPIKey:          "Clave API inválida",
+			OriginNotAllowed:       "Origen no permitido",
+			CaptchaFailed:          "Verificación captcha fallida",
+			InvalidDeviceID:        "ID de dispositivo no válido",
+			InvalidFeedback:        "Comentarios no válidos",
+			ClassificationNotFound: "Clasificación no encontrada",
+			IdempotencyKeyReused:   "La clave de idempotencia ya se usó para otra solicitud",
 		},
 		"fr": {
-			InvalidPostalCode: "Code postal allemand invalide",
-			InvalidImage:      "Fichier image invalide",
-			RecaptchaFailed:   "Échec de la vérification reCAPTCHA",
-			ProcessingError:   "Erreur lors du traitement de votre demande",
-			MissingFields:     "Champs requis manquants",
+			InvalidPostalCode:      "Code postal allemand invalide",
+			InvalidImage:           "Fichier image invalide",
+			RecaptchaFailed:        "Échec de la vérification reCAPTCHA",
+			ProcessingError:        "Erreur lors du traitement de votre demande",
+			MissingFields:          "Champs requis manquants",
+			TooManyImages:          "Trop d'images",
+			ImagesTooLarge:         "Les images sont trop volumineuses",
+			InvalidQuery:           "Description de l'objet invalide",
+			RateLimited:            "Trop de requêtes, veuillez réessayer plus tard",
+			InvalidAPIKey:          "Clé API invalide",
+			OriginNotAllowed:       "Origine non autorisée",
+			CaptchaFailed:          "Échec de la vérification captcha",
+			InvalidDeviceID:        "Identifiant d’appareil non valide",
+			InvalidFeedback:        "Avis non valide",
+			ClassificationNotFound: "Classification introuvable",
+			IdempotencyKeyReused:   "La clé d’idempotence a déjà été utilisée pour une autre requête",
 		},
 		"hi": {
-			InvalidPostalCode: "अमान्य जर्मन पोस्टल कोड",
-			InvalidImage:      "अमान्य छवि फ़ाइल",
-			RecaptchaFailed:   "reCAPTCHA सत्यापन विफल",
-			ProcessingError:   "आपके अनुरोध को संसाधित करने में त्रुटि",
-			MissingFields:     "आवश्यक फ़ील्ड गुम हैं",
+			InvalidPostalCode:      "अमान्य जर्मन पोस्टल कोड",
+			InvalidImage:           "अमान्य छवि फ़ाइल",
+			RecaptchaFailed:        "reCAPTCHA सत्यापन विफल",
+			ProcessingError:        "आपके अनुरोध को संसाधित करने में त्रुटि",
+			MissingFields:          "आवश्यक फ़ील्ड गुम हैं",
+			TooManyImages:          "बहुत अधिक छवियाँ",
+			ImagesTooLarge:         "छवियाँ बहुत बड़ी हैं",
+			InvalidQuery:           "अमान्य वस्तु विवरण",
+			RateLimited:            "बहुत अधिक अनुरोध, कृपया बाद में पुनः प्रयास करें",
+			InvalidAPIKey:          "अमान्य API कुंजी",
+			OriginNotAllowed:       "मूल की अनुमति नहीं है",
+			CaptchaFailed:          "कैप्चा सत्यापन विफल",
+			InvalidDeviceID:        "अमान्य डिवाइस आईडी",
+			InvalidFeedback:        "अमान्य प्रतिक्रिया",
+			ClassificationNotFound: "वर्गीकरण नहीं मिला",
+			IdempotencyKeyReused:   "इडेम्पोटेंसी कुंजी पहले ही किसी अन्य अनुरोध के लिए उपयोग की जा चुकी है",
 		},
 		"ur": {
-			InvalidPostalCode: "غلط جرمن پوسٹل کوڈ",
-			InvalidImage:      "غلط تصویری فائل",
-			RecaptchaFailed:   "reCAPTCHA تصدیق ناکام",
-			ProcessingError:   "آپ کی درخواست پر عمل کرنے میں خرابی",
-			MissingFields:     "ضروری فیلڈز غائب ہیں",
+			InvalidPostalCode:      "غلط جرمن پوسٹل کوڈ",
+			InvalidImage:           "غلط تصویری فائل",
+			RecaptchaFailed:        "reCAPTCHA تصدیق ناکام",
+			ProcessingError:        "آپ کی درخواست پر عمل کرنے میں خرابی",
+			MissingFields:          "ضروری فیلڈز غائب ہیں",
+			TooManyImages:          "بہت زیادہ تصاویر",
+			ImagesTooLarge:         "تصاویر بہت بڑی ہیں",
+			InvalidQuery:           "غلط شے کی تفصیل",
+			RateLimited:            "بہت زیادہ درخواستیں، براہ کرم بعد میں دوبارہ کوشش کریں",
+			InvalidAPIKey:          "غلط API کلید",
+			OriginNotAllowed:       "ماخذ کی اجازت نہیں ہے",
+			CaptchaFailed:          "کیپچا تصدیق ناکام",
+			InvalidDeviceID:        "ڈیوائس آئی ڈی غلط ہے",
+			InvalidFeedback:        "غلط رائے",
+			ClassificationNotFound: "درجہ بندی نہیں ملی",
+			IdempotencyKeyReused:   "آئیڈیمپوٹینسی کلید پہلے ہی کسی اور درخواست کے لیے استعمال ہو چکی ہے",
 		},
 		"vi": {
-			InvalidPostalCode: "Mã bưu điện Đức không hợp lệ",
-			InvalidImage:      "Tệp hình ảnh không hợp lệ",
-			RecaptchaFailed:   "Xác minh reCAPTCHA thất bại",
-			ProcessingError:   "Lỗi xử lý yêu cầu của bạn",
-			MissingFields:     "Thiếu các trường bắt buộc",
+			InvalidPostalCode:      "Mã bưu điện Đức không hợp lệ",
+			InvalidImage:           "Tệp hình ảnh không hợp lệ",
+			RecaptchaFailed:        "Xác minh reCAPTCHA thất bại",
+			ProcessingError:        "Lỗi xử lý yêu cầu của bạn",
+			MissingFields:          "Thiếu các trường bắt buộc",
+			TooManyImages:          "Quá nhiều hình ảnh",
+			ImagesTooLarge:         "Hình ảnh quá lớn",
+			InvalidQuery:           "Mô tả vật phẩm không hợp lệ",
+			RateLimited:            "Quá nhiều yêu cầu, vui lòng thử lại sau",
+			InvalidAPIKey:          "Khóa API không hợp lệ",
+			OriginNotAllowed:       "Nguồn gốc không được phép",
+			CaptchaFailed:          "Xác minh captcha thất bại",
+			InvalidDeviceID:        "ID thiết bị không hợp lệ",
+			InvalidFeedback:        "Phản hồi không hợp lệ",
+			ClassificationNotFound: "Không tìm thấy phân loại",
+			IdempotencyKeyReused:   "Khóa idempotency đã được dùng cho một yêu cầu khác",
 		},
 		"zh": {
-			InvalidPostalCode: "无效的德国邮政编码",
-			InvalidImage:      "无效的图像文件",
-			RecaptchaFailed:   "reCAPTCHA验证失败",
-			ProcessingError:   "处理您的请求时出错",
-			MissingFields:     "缺少必填字段",
+			InvalidPostalCode:      "无效的德国邮政编码",
+			InvalidImage:           "无效的图像文件",
+			RecaptchaFailed:        "reCAPTCHA验证失败",
+			ProcessingError:        "处理您的请求时出错",
+			MissingFields:          "缺少必填字段",
+			TooManyImages:          "图片过多",
+			ImagesTooLarge:         "图片过大",
+			InvalidQuery:           "无效的物品描述",
+			RateLimited:            "请求过多，请稍后再试",
+			InvalidAPIKey:          "无效的API密钥",
+			OriginNotAllowed:       "不允许的来源",
+			CaptchaFailed:          "验证码验证失败",
+			InvalidDeviceID:        "无效的设备 ID",
+			InvalidFeedback:        "无效的反馈",
+			ClassificationNotFound: "未找到分类",
+			IdempotencyKeyReused:   "该幂等键已用于其他请求",
 		},
 		"fa": {
-			InvalidPostalCode: "کد پستی آلمان نامعتبر",
-			InvalidImage:      "فایل تصویر نامعتبر",
-			RecaptchaFailed:   "تأیید reCAPTCHA ناموفق",
-			ProcessingError:   "خطا در پردازش درخواست شما",
-			MissingFields:     "فیلدهای ضروری موجود نیست",
+			InvalidPostalCode:      "کد پستی آلمان نامعتبر",
+			InvalidImage:           "فایل تصویر نامعتبر",
+			RecaptchaFailed:        "تأیید reCAPTCHA ناموفق",
+			ProcessingError:        "خطا در پردازش درخواست شما",
+			MissingFields:          "فیلدهای ضروری موجود نیست",
+			TooManyImages:          "تعداد تصاویر بیش از حد است",
+			ImagesTooLarge:         "تصاویر بیش از حد بزرگ هستند",
+			InvalidQuery:           "توضیحات مورد نامعتبر",
+			RateLimited:            "درخواست‌های بیش از حد، لطفاً بعداً دوباره تلاش کنید",
+			InvalidAPIKey:          "کلید API نامعتبر",
+			OriginNotAllowed:       "مبدأ مجاز نیست",
+			CaptchaFailed:          "تأیید کپچا ناموفق",
+			InvalidDeviceID:        "شناسه دستگاه نامعتبر است",
+			InvalidFeedback:        "بازخورد نامعتبر است",
+			ClassificationNotFound: "طبقه‌بندی پیدا نشد",
+			IdempotencyKeyReused:   "کلید idempotency قبلاً برای درخواست دیگری استفاده شده است",
 		},
 		"ps": {
-			InvalidPostalCode: "د آلمان د پوستې غلط کوډ",
-			InvalidImage:      "د انځور غلط دوتنه",
-			RecaptchaFailed:   "د reCAPTCHA تصدیق ناکام",
-			ProcessingError:   "ستاسو د غوښتنې پروسس کولو کې تېروتنه",
-			MissingFields:     "اړین ساحې ورک دي",
+			InvalidPostalCode:      "د آلمان د پوستې غلط کوډ",
+			InvalidImage:           "د انځور غلط دوتنه",
+			RecaptchaFailed:        "د reCAPTCHA تصدیق ناکام",
+			ProcessingError:        "ستاسو د غوښتنې پروسس کولو کې تېروتنه",
+			MissingFields:          "اړین ساحې ورک دي",
+			TooManyImages:          "ډېر زيات انځورونه",
+			ImagesTooLarge:         "انځورونه ډېر لوی دي",
+			InvalidQuery:           "د توکي غلط تشریح",
+			RateLimited:            "ډېرې غوښتنې، مهرباني وکړئ وروسته بیا هڅه وکړئ",
+			InvalidAPIKey:          "غلط API کیلي",
+			OriginNotAllowed:       "سرچینې ته اجازه نشته",
+			CaptchaFailed:          "د کیپچا تصدیق ناکام",
+			InvalidDeviceID:        "د وسیلې پېژندنه ناسمه ده",
+			InvalidFeedback:        "ناسم نظر",
+			ClassificationNotFound: "طبقه بندي ونه موندل شوه",
+			IdempotencyKeyReused:   "د idempotency کیلي دمخه د بلې غوښتنې لپاره کارول شوې ده",
 		},
 		"ta": {
-			InvalidPostalCode: "தவறான ஜெர்மன் அஞ்சல் குறியீடு",
-			InvalidImage:      "தவறான படக் கோப்பு",
-			RecaptchaFailed:   "reCAPTCHA சரிபார்ப்பு தோல்வி",
-			ProcessingError:   "உங்கள் கோரிக்கையை செயலாக்குவதில் பிழை",
-			MissingFields:     "தேவையான புலங்கள் காணவில்லை",
+			InvalidPostalCode:      "தவறான ஜெர்மன் அஞ்சல் குறியீடு",
+			InvalidImage:           "தவறான படக் கோப்பு",
+			RecaptchaFailed:        "reCAPTCHA சரிபார்ப்பு தோல்வி",
+			ProcessingError:        "உங்கள் கோரிக்கையை செயலாக்குவதில் பிழை",
+			MissingFields:          "தேவையான புலங்கள் காணவில்லை",
+			TooManyImages:          "அதிகமான படங்கள்",
+			ImagesTooLarge:         "படங்கள் மிகப் பெரியவை",
+			InvalidQuery:           "தவறான பொருள் விளக்கம்",
+			RateLimited:            "அதிகமான கோரிக்கைகள், பின்னர் மீண்டும் முயற்சிக்கவும்",
+			InvalidAPIKey:          "தவறான API விசை",
+			OriginNotAllowed:       "மூலம் அனுமதிக்கப்படவில்லை",
+			CaptchaFailed:          "கேப்ட்சா சரிபார்ப்பு தோல்வி",
+			InvalidDeviceID:        "தவறான சாதன ஐடி",
+			InvalidFeedback:        "தவறான கருத்து",
+			ClassificationNotFound: "வகைப்பாடு கிடைக்கவில்லை",
+			IdempotencyKeyReused:   "இந்த idempotency விசை ஏற்கனவே வேறு கோரிக்கைக்கு பயன்படுத்தப்பட்டது",
 		},
 		"sq": {
-			InvalidPostalCode: "Kod postar gjerman i pavlefshëm",
-			InvalidImage:      "Skedar imazhi i pavlefshëm",
-			RecaptchaFailed:   "Verifikimi reCAPTCHA dështoi",
-			ProcessingError:   "Gabim në përpunimin e kërkesës suaj",
-			MissingFields:     "Mungojnë fushat e detyrueshme",
+			InvalidPostalCode:      "Kod postar gjerman i pavlefshëm",
+			InvalidImage:           "Skedar imazhi i pavlefshëm",
+			RecaptchaFailed:        "Verifikimi reCAPTCHA dështoi",
+			ProcessingError:        "Gabim në përpunimin e kërkesës suaj",
+			MissingFields:          "Mungojnë fushat e detyrueshme",
+			TooManyImages:          "Shumë imazhe",
+			ImagesTooLarge:         "Imazhet janë shumë të mëdha",
+			InvalidQuery:           "Përshkrim i pavlefshëm i sendit",
+			RateLimited:            "Shumë kërkesa, ju lutemi provoni përsëri më vonë",
+			InvalidAPIKey:          "Çelës API i pavlefshëm",
+			OriginNotAllowed:       "Origjina nuk lejohet",
+			CaptchaFailed:          "Verifikimi captcha dështoi",
+			InvalidDeviceID:        "ID e pajisjes e pavlefshme",
+			InvalidFeedback:        "Koment i pavlefshëm",
+			ClassificationNotFound: "Klasifikimi nuk u gjet",
+			IdempotencyKeyReused:   "Çelësi i idempotencës është përdorur tashmë për një kërkesë tjetër",
 		},
 		"da": {
-			InvalidPostalCode: "Ugyldig tysk postnummer",
-			InvalidImage:      "Ugyldig billedfil",
-			RecaptchaFailed:   "reCAPTCHA-verifikation mislykkedes",
-			ProcessingError:   "Fejl ved behandling af din anmodning",
-			MissingFields:     "Manglende påkrævede felter",
+			InvalidPostalCode:      "Ugyldig tysk postnummer",
+			InvalidImage:           "Ugyldig billedfil",
+			RecaptchaFailed:        "reCAPTCHA-verifikation mislykkedes",
+			ProcessingError:        "Fejl ved behandling af din anmodning",
+			MissingFields:          "Manglende påkrævede felter",
+			TooManyImages:          "For mange billeder",
+			ImagesTooLarge:         "Billederne er for store",
+			InvalidQuery:           "Ugyldig beskrivelse af genstanden",
+			RateLimited:            "For mange anmodninger, prøv igen senere",
+			InvalidAPIKey:          "Ugyldig API-nøgle",
+			OriginNotAllowed:       "Oprindelse ikke tilladt",
+			CaptchaFailed:          "Captcha-verifikation mislykkedes",
+			InvalidDeviceID:        "Ugyldigt enheds-id",
+			InvalidFeedback:        "Ugyldig feedback",
+			ClassificationNotFound: "Klassificering ikke fundet",
+			IdempotencyKeyReused:   "Idempotensnøglen er allerede brugt til en anden anmodning",
 		},
 		"uk": {
-			InvalidPostalCode: "Недійсний німецький поштовий індекс",
-			InvalidImage:      "Недійсний файл зображення",
-			RecaptchaFailed:   "Перевірка reCAPTCHA не вдалася",
-			ProcessingError:   "Помилка обробки вашого запиту",
-			MissingFields:     "Відсутні обов'язкові поля",
+			InvalidPostalCode:      "Недійсний німецький поштовий індекс",
+			InvalidImage:           "Недійсний файл зображення",
+			RecaptchaFailed:        "Перевірка reCAPTCHA не вдалася",
+			ProcessingError:        "Помилка обробки вашого запиту",
+			MissingFields:          "Відсутні обов'язкові поля",
+			TooManyImages:          "Забагато зображень",
+			ImagesTooLarge:         "Зображення завеликі",
+			InvalidQuery:           "Недійсний опис предмета",
+			RateLimited:            "Забагато запитів, будь ласка, спробуйте пізніше",
+			InvalidAPIKey:          "Недійсний API-ключ",
+			OriginNotAllowed:       "Джерело запиту не дозволено",
+			CaptchaFailed:          "Перевірка капчі не вдалася",
+			InvalidDeviceID:        "Недійсний ідентифікатор пристрою",
+			InvalidFeedback:        "Недійсний відгук",
+			ClassificationNotFound: "Класифікацію не знайдено",
+			IdempotencyKeyReused:   "Ключ ідемпотентності вже використано для іншого запиту",
 		},
 	}
 
@@ -214,38 +502,73 @@ func (l *Localizer) IsLanguageSupported(language string) bool {
 	return l.supportedLanguages[language]
 }
 
+// LanguageFromHeader returns the first supported language of an Accept-Language header, or German
+func (l *Localizer) LanguageFromHeader(acceptLanguage string) string {
+	for _, entry := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(entry), ";")
+		language, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if l.IsLanguageSupported(language) {
+			return language
+		}
+	}
+
+	return "de"
+}
+
 // GetErrorMessage returns localized error message
 func (l *Localizer) GetErrorMessage(language, messageType string) string {
 	if messages, exists := l.errorMessages[language]; exists {
-		switch messageType {
-		case "invalid_postal_code":
-			return messages.InvalidPostalCode
-		case "invalid_image":
-			return messages.InvalidImage
-		case "recaptcha_failed":
-			return messages.RecaptchaFailed
-		case "processing_error":
-			return messages.ProcessingError
-		case "missing_fields":
-			return messages.MissingFields
+		if message := messages.get(messageType); message != "" {
+			return message
 		}
 	}
 
 	// Fallback to German if the requested language is not available
 	if messages, exists := l.errorMessages["de"]; exists {
-		switch messageType {
-		case "invalid_postal_code":
-			return messages.InvalidPostalCode
-		case "invalid_image":
-			return messages.InvalidImage
-		case "recaptcha_failed":
-			return messages.RecaptchaFailed
-		case "processing_error":
-			return messages.ProcessingError
-		case "missing_fields":
-			return messages.MissingFields
+		if message := messages.get(messageType); message != "" {
+			return message
 		}
 	}
 
 	return "An error occurred"
 }
+
+// get returns the message for the message type, or an empty string if it is unknown
+func (m ErrorMessages) get(messageType string) string {
+	switch messageType {
+	case "invalid_postal_code":
+		return m.InvalidPostalCode
+	case "invalid_image":
+		return m.InvalidImage
+	case "recaptcha_failed":
+		return m.RecaptchaFailed
+	case "processing_error":
+		return m.ProcessingError
+	case "missing_fields":
+		return m.MissingFields
+	case "too_many_images":
+		return m.TooManyImages
+	case "images_too_large":
+		return m.ImagesTooLarge
+	case "invalid_query":
+		return m.InvalidQuery
+	case "rate_limited":
+		return m.RateLimited
+	case "invalid_api_key":
+		return m.InvalidAPIKey
+	case "origin_not_allowed":
+		return m.OriginNotAllowed
+	case "captcha_failed":
+		return m.CaptchaFailed
+	case "invalid_device_id":
+		return m.InvalidDeviceID
+	case "invalid_feedback":
+		return m.InvalidFeedback
+	case "classification_not_found":
+		return m.ClassificationNotFound
+	case "idempotency_key_reused":
+		return m.IdempotencyKeyReused
+	}
+
+	return ""
+}
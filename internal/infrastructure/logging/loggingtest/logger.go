@@ -0,0 +1,83 @@
+// Package loggingtest provides a logger recording its entries in memory for tests
+package loggingtest
+
+import (
+	"context"
+	"sync"
+)
+
+// Entry is a log entry recorded by the Logger
+type Entry struct {
+	Severity string
+	Payload  interface{}
+}
+
+// Logger records log entries in memory, it implements logging.Logger
+type Logger struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewLogger creates a new in-memory logger
+func NewLogger() *Logger {
+	return &Logger{}
+}
+
+// Debug records a debug entry
+func (l *Logger) Debug(_ context.Context, payload interface{}) {
+	l.record("DEBUG", payload)
+}
+
+// Info records an info entry
+func (l *Logger) Info(_ context.Context, payload interface{}) {
+	l.record("INFO", payload)
+}
+
+// Warning records a warning entry
+func (l *Logger) Warning(_ context.Context, payload interface{}) {
+	l.record("WARNING", payload)
+}
+
+// Error records an error entry
+func (l *Logger) Error(_ context.Context, payload interface{}) {
+	l.record("ERROR", payload)
+}
+
+// Critical records a critical entry
+func (l *Logger) Critical(_ context.Context, payload interface{}) {
+	l.record("CRITICAL", payload)
+}
+
+// Close does nothing, the entries stay available
+func (l *Logger) Close(_ context.Context) error {
+	return nil
+}
+
+// Entries returns the recorded entries in order
+func (l *Logger) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]Entry(nil), l.entries...)
+}
+
+// Messages returns the message field of the recorded map payloads in order
+func (l *Logger) Messages() []string {
+	var messages []string
+	for _, entry := range l.Entries() {
+		if payload, ok := entry.Payload.(map[string]interface{}); ok {
+			if message, ok := payload["message"].(string); ok {
+				messages = append(messages, message)
+			}
+		}
+	}
+
+	return messages
+}
+
+func (l *Logger) record(severity string, payload interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, Entry{Severity: severity, Payload: payload})
+}
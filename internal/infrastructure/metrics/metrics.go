@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Token types of the Gemini token usage counter
+const (
+	TokenTypeInput  = "input"
+	TokenTypeOutput = "output"
+)
+
+// Caches of the cache lookup counter
+const (
+	CacheClassification = "classification"
+	CacheTranslation    = "translation"
+)
+
+// durationBuckets are the histogram boundaries in seconds, from cache hits to slow Gemini calls
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30}
+
+// scoreBuckets are the histogram boundaries of captcha risk scores, which range from 0 to 1
+var scoreBuckets = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1}
+
+// The instruments are created from the global meter provider, so they record nothing until
+// libs/meter has set it up. Creation only fails for invalid names, so the errors are ignored.
+var (
+	meter = otel.Meter("github.com/DeryabinSergey/waste-tips-backend")
+
+	requests, _ = meter.Int64Counter("http.server.requests",
+		metric.WithDescription("Handled requests by route, status code and language"),
+		metric.WithUnit("{request}"))
+	requestDuration, _ = meter.Float64Histogram("http.server.request.duration",
+		metric.WithDescription("Duration of handled requests"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...))
+	stageDuration, _ = meter.Float64Histogram("pipeline.stage.duration",
+		metric.WithDescription("Duration of the classification pipeline stages"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...))
+	tokenUsage, _ = meter.Int64Counter("gen_ai.client.token.usage",
+		metric.WithDescription("Gemini tokens by model and token type"),
+		metric.WithUnit("{token}"))
+	captchaScore, _ = meter.Float64Histogram("captcha.score",
+		metric.WithDescription("Risk scores of captcha assessments, 1 is very likely a human"),
+		metric.WithExplicitBucketBoundaries(scoreBuckets...))
+	cacheLookups, _ = meter.Int64Counter("cache.lookups",
+		metric.WithDescription("Cache lookups by cache and result, the hit ratio is hits over all lookups"),
+		metric.WithUnit("{lookup}"))
+)
+
+// RecordRequest counts a handled request and records its duration.
+// The language is empty for requests without one.
+func RecordRequest(ctx context.Context, route string, statusCode int, language string, duration time.Duration) {
+	attrs := metric.WithAttributes(
+		attribute.String("http.route", route),
+		attribute.String("http.response.status_code", strconv.Itoa(statusCode)),
+		attribute.String("request.language", language),
+	)
+	requests.Add(ctx, 1, attrs)
+	requestDuration.Record(ctx, duration.Seconds(), attrs)
+}
+
+// RecordStage records the duration of a pipeline stage, named like its span
+func RecordStage(ctx context.Context, stage string, duration time.Duration) {
+	stageDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(attribute.String("pipeline.stage", stage)))
+}
+
+// RecordTokens counts the tokens of a Gemini call
+func RecordTokens(ctx context.Context, model, tokenType string, count int) {
+	if count <= 0 {
+		return
+	}
+	tokenUsage.Add(ctx, int64(count), metric.WithAttributes(
+		attribute.String("gen_ai.request.model", model),
+		attribute.String("gen_ai.token.type", tokenType),
+	))
+}
+
+// RecordCaptchaScore records the risk score of a captcha assessment
+func RecordCaptchaScore(ctx context.Context, provider string, score float64) {
+	captchaScore.Record(ctx, score, metric.WithAttributes(attribute.String("captcha.provider", provider)))
+}
+
+// RecordCacheLookup counts a lookup in the cache
+func RecordCacheLookup(ctx context.Context, cache string, hit bool) {
+	cacheLookups.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("cache", cache),
+		attribute.Bool("cache.hit", hit),
+	))
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IPResolver resolves the IP of the client behind the trusted proxies
+type IPResolver struct {
+	trustedProxies []*net.IPNet
+}
+
+// NewIPResolver creates a new resolver. Trusted proxies are CIDR ranges whose X-Forwarded-For entries are honoured.
+func NewIPResolver(trustedProxies []string) (*IPResolver, error) {
+	networks := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, cidr := range trustedProxies {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+
+	return &IPResolver{trustedProxies: networks}, nil
+}
+
+// ClientIP returns the IP of the client. X-Forwarded-For is walked from the right,
+// skipping trusted proxies, so clients cannot spoof their address by prepending entries.
+func (l *IPResolver) ClientIP(r *http.Request) string {
+	remoteIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	if !l.isTrusted(remoteIP) {
+		return remoteIP
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(forwarded[i])
+		if ip == "" {
+			continue
+		}
+		if !l.isTrusted(ip) {
+			return ip
+		}
+		remoteIP = ip
+	}
+
+	return remoteIP
+}
+
+func (l *IPResolver) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, network := range l.trustedProxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
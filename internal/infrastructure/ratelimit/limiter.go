@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/auth"
+)
+
+// Limiter applies token bucket limits per client IP or per API key
+type Limiter struct {
+	*IPResolver
+	store    Store
+	ipLimit  Limit
+	keyLimit Limit
+}
+
+// NewLimiter creates a new limiter, clients are identified by their IP behind the trusted proxies of the resolver
+func NewLimiter(store Store, ipLimit, keyLimit Limit, resolver *IPResolver) *Limiter {
+	return &Limiter{
+		IPResolver: resolver,
+		store:      store,
+		ipLimit:    ipLimit,
+		keyLimit:   keyLimit,
+	}
+}
+
+// Allow takes a token for the client of the request.
+// Authenticated clients are limited per API key with their own quota if they have one,
+// all others per client IP.
+func (l *Limiter) Allow(ctx context.Context, r *http.Request) (Result, error) {
+	if client := auth.ClientFromContext(ctx); client != nil {
+		limit := l.keyLimit
+		if client.RequestsPerMinute > 0 {
+			limit = PerMinute(client.RequestsPerMinute, max(1, client.Burst))
+		}
+		return l.store.Take(ctx, "key:"+client.ID, limit)
+	}
+
+	return l.store.Take(ctx, "ip:"+l.ClientIP(r), l.ipLimit)
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestMemoryStore returns a store whose clock is advanced by the returned function
+func newTestMemoryStore() (*MemoryStore, func(time.Duration)) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	return store, func(d time.Duration) {
+		now = now.Add(d)
+	}
+}
+
+func TestMemoryStoreTakesUpToBurst(t *testing.T) {
+	ctx := context.Background()
+	store, advance := newTestMemoryStore()
+	limit := Limit{Rate: 2, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if result, _ := store.Take(ctx, "client", limit); !result.Allowed {
+			t.Fatalf("Take() %d was rejected within the burst", i+1)
+		}
+	}
+
+	result, _ := store.Take(ctx, "client", limit)
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("Take() beyond the burst = %+v, want rejected with 500ms retry", result)
+	}
+
+	advance(500 * time.Millisecond)
+	if result, _ := store.Take(ctx, "client", limit); !result.Allowed {
+		t.Error("Take() after a refilled token was rejected")
+	}
+}
+
+func TestMemoryStoreAllowsDisabledLimits(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestMemoryStore()
+
+	for _, limit := range []Limit{PerMinute(0, 5), PerMinute(10, 0), PerMinute(-1, 5)} {
+		for i := 0; i < 10; i++ {
+			result, err := store.Take(ctx, "client", limit)
+			if err != nil {
+				t.Fatalf("Take() error = %v", err)
+			}
+			if !result.Allowed || result.RetryAfter != 0 {
+				t.Fatalf("Take() with %+v = %+v, want allowed", limit, result)
+			}
+		}
+	}
+	if len(store.buckets) != 0 {
+		t.Error("a bucket was stored for a disabled limit")
+	}
+}
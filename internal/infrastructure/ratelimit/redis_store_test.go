@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisStore returns a store whose clock is advanced by the returned function
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis, func(time.Duration)) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	now := time.Unix(1700000000, 0)
+	store := NewRedisStore(client, "test:")
+	store.now = func() time.Time { return now }
+
+	return store, server, func(d time.Duration) {
+		now = now.Add(d)
+		server.FastForward(d)
+	}
+}
+
+func TestRedisStoreTakesUpToBurst(t *testing.T) {
+	ctx := context.Background()
+	store, _, _ := newTestRedisStore(t)
+	limit := Limit{Rate: 1, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		result, err := store.Take(ctx, "client", limit)
+		if err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("Take() %d was rejected within the burst", i+1)
+		}
+	}
+
+	result, err := store.Take(ctx, "client", limit)
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if result.Allowed {
+		t.Fatal("Take() beyond the burst was allowed")
+	}
+	if result.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v, want 1s", result.RetryAfter)
+	}
+
+	// Buckets are separate per key
+	if result, _ := store.Take(ctx, "other", limit); !result.Allowed {
+		t.Error("Take() for another key was rejected")
+	}
+}
+
+func TestRedisStoreRefillsAtRate(t *testing.T) {
+	ctx := context.Background()
+	store, _, advance := newTestRedisStore(t)
+	limit := Limit{Rate: 2, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		store.Take(ctx, "client", limit)
+	}
+
+	advance(250 * time.Millisecond)
+	result, err := store.Take(ctx, "client", limit)
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if result.Allowed || result.RetryAfter != 250*time.Millisecond {
+		t.Fatalf("Take() after half a token = %+v, want rejected with 250ms retry", result)
+	}
+
+	advance(250 * time.Millisecond)
+	if result, _ := store.Take(ctx, "client", limit); !result.Allowed {
+		t.Fatal("Take() after a refilled token was rejected")
+	}
+
+	// The bucket never holds more than the burst
+	advance(time.Hour)
+	for i := 0; i < 2; i++ {
+		store.Take(ctx, "client", limit)
+	}
+	if result, _ := store.Take(ctx, "client", limit); result.Allowed {
+		t.Error("bucket refilled beyond the burst")
+	}
+}
+
+func TestRedisStoreExpiresFullBuckets(t *testing.T) {
+	ctx := context.Background()
+	store, server, advance := newTestRedisStore(t)
+	limit := Limit{Rate: 1, Burst: 5}
+
+	store.Take(ctx, "client", limit)
+	if ttl := server.TTL("test:client"); ttl != 6*time.Second {
+		t.Errorf("bucket TTL = %v, want the refill time of the burst plus a second", ttl)
+	}
+
+	advance(6 * time.Second)
+	if server.Exists("test:client") {
+		t.Error("bucket is still stored after it refilled")
+	}
+}
+
+func TestRedisStoreAllowsDisabledLimits(t *testing.T) {
+	ctx := context.Background()
+	store, server, _ := newTestRedisStore(t)
+
+	for _, limit := range []Limit{PerMinute(0, 5), PerMinute(10, 0)} {
+		for i := 0; i < 10; i++ {
+			result, err := store.Take(ctx, "client", limit)
+			if err != nil {
+				t.Fatalf("Take() error = %v", err)
+			}
+			if !result.Allowed || result.RetryAfter != 0 {
+				t.Fatalf("Take() with %+v = %+v, want allowed", limit, result)
+			}
+		}
+	}
+	if server.Exists("test:client") {
+		t.Error("a bucket was stored for a disabled limit")
+	}
+}
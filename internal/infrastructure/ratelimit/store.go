@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit describes a token bucket: tokens are refilled at Rate per second up to Burst.
+// A limit without a positive rate and burst is disabled and allows every request.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Disabled reports whether the limit allows every request
+func (l Limit) Disabled() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// PerMinute creates a limit of requests per minute with the given burst
+func PerMinute(requests, burst int) Limit {
+	return Limit{Rate: float64(requests) / 60, Burst: burst}
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Store defines the storage of token buckets
+type Store interface {
+	// Take removes a token from the bucket identified by key, disabled limits are always allowed
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
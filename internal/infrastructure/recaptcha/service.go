@@ -5,6 +5,13 @@ import (
 	"cloud.google.com/go/recaptchaenterprise/v2/apiv1/recaptchaenterprisepb"
 	"context"
 	"fmt"
+	"github.com/DeryabinSergey/waste-tips-backend/internal/infrastructure/metrics"
+	"github.com/DeryabinSergey/waste-tips-backend/libs/tracer"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 )
 
 // Service handles reCAPTCHA Enterprise verification
@@ -27,7 +34,10 @@ func (s *Service) VerifyToken(ctx context.Context, token string) (bool, error) {
 		return false, fmt.Errorf("missing reCAPTCHA configuration")
 	}
 
-	client, err := recaptchaenterprise.NewClient(ctx)
+	// The trace context is propagated to the assessment call
+	client, err := recaptchaenterprise.NewClient(ctx, option.WithGRPCDialOption(
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler(otelgrpc.WithPropagators(tracer.Propagator()))),
+	))
 	if err != nil {
 		return false, fmt.Errorf("error creating reCAPTCHA client: %v", err)
 	}
@@ -48,6 +58,16 @@ func (s *Service) VerifyToken(ctx context.Context, token string) (bool, error) {
 		return false, fmt.Errorf("error creating reCAPTCHA assessment: %v", err)
 	}
 
+	// Responses without token properties or a risk analysis are treated as invalid
+	valid := response.GetTokenProperties().GetValid()
+	score := response.GetRiskAnalysis().GetScore()
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.Bool("captcha.token_valid", valid))
+	if response.GetRiskAnalysis() != nil {
+		span.SetAttributes(attribute.Float64("captcha.score", float64(score)))
+		metrics.RecordCaptchaScore(ctx, "recaptcha", float64(score))
+	}
+
 	// Check if token is valid and score is acceptable
-	return response.TokenProperties.Valid && response.RiskAnalysis.Score >= 0.5, nil
+	return valid && score >= 0.5, nil
 }
@@ -9,4 +9,4 @@ import (
 type Tracer interface {
 	Start(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span)
 	Close(ctx context.Context) error
-}
\ No newline at end of file
+}
@@ -0,0 +1,47 @@
+// Package tracingtest provides a tracer recording its spans in memory for tests
+package tracingtest
+
+import (
+	"context"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer records spans in memory, it implements tracing.Tracer
+type Tracer struct {
+	recorder *tracetest.SpanRecorder
+	provider *sdktrace.TracerProvider
+	tracer   trace.Tracer
+}
+
+// NewTracer creates a new in-memory tracer sampling all spans
+func NewTracer() *Tracer {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSpanProcessor(recorder),
+	)
+
+	return &Tracer{
+		recorder: recorder,
+		provider: provider,
+		tracer:   provider.Tracer("memory"),
+	}
+}
+
+// Start starts a new span
+func (t *Tracer) Start(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, spanName, opts...)
+}
+
+// Close does nothing, the spans stay available
+func (t *Tracer) Close(_ context.Context) error {
+	return nil
+}
+
+// Spans returns the ended spans in the order they ended
+func (t *Tracer) Spans() []sdktrace.ReadOnlySpan {
+	return t.recorder.Ended()
+}
@@ -0,0 +1,67 @@
+package meter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	otelprometheus "go.opentelemetry.io/otel/exporters/prometheus"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+// Meter owns the meter provider and the Prometheus registry its metrics are exposed from
+type Meter struct {
+	mp       *sdkmetric.MeterProvider
+	registry *prometheus.Registry
+}
+
+// Init sets up the global meter provider. Metrics are always exposed to Prometheus through Handler,
+// and pushed to the OTLP endpoint from the OTEL_EXPORTER_OTLP_* environment variables if otlp is set.
+func Init(ctx context.Context, applicationName string, otlp bool) (*Meter, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceNameKey.String(applicationName)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := prometheus.NewRegistry()
+	promExporter, err := otelprometheus.New(otelprometheus.WithRegisterer(registry))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []sdkmetric.Option{
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(promExporter),
+	}
+	if otlp {
+		exporter, err := otlpmetricgrpc.New(ctx)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)))
+	}
+
+	meterProvider := sdkmetric.NewMeterProvider(opts...)
+	otel.SetMeterProvider(meterProvider)
+	return &Meter{mp: meterProvider, registry: registry}, nil
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Meter) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Close flushes the pending metrics to the OTLP endpoint and shuts the meter provider down
+func (m *Meter) Close(ctx context.Context) error {
+	if m.mp == nil {
+		return nil
+	}
+	return m.mp.Shutdown(ctx)
+}
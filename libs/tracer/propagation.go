@@ -0,0 +1,96 @@
+package tracer
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CloudTraceContextHeader is the trace header set by Google Cloud load balancers and App Engine
+const CloudTraceContextHeader = "X-Cloud-Trace-Context"
+
+// Propagator returns the propagator of trace context across services: W3C trace context and baggage,
+// and Google Cloud's trace header for incoming requests without a traceparent
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(
+		CloudTraceContext{},
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	)
+}
+
+// CloudTraceContext extracts X-Cloud-Trace-Context headers, formatted TRACE_ID/SPAN_ID;o=OPTIONS
+// with a decimal span ID. Outgoing requests carry traceparent only, so it does not inject.
+type CloudTraceContext struct{}
+
+// Inject does nothing, the W3C propagator injects the trace context
+func (CloudTraceContext) Inject(context.Context, propagation.TextMapCarrier) {}
+
+// Extract returns a copy of ctx with the remote span context of the header, if it is valid
+func (CloudTraceContext) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	header := carrier.Get(CloudTraceContextHeader)
+	if header == "" {
+		return ctx
+	}
+
+	traceIDValue, rest, found := strings.Cut(header, "/")
+	if !found {
+		return ctx
+	}
+	spanIDValue, options, _ := strings.Cut(rest, ";")
+
+	traceID, err := trace.TraceIDFromHex(traceIDValue)
+	if err != nil {
+		return ctx
+	}
+	spanIDNumber, err := strconv.ParseUint(spanIDValue, 10, 64)
+	if err != nil || spanIDNumber == 0 {
+		return ctx
+	}
+	var spanID trace.SpanID
+	for i := range spanID {
+		spanID[i] = byte(spanIDNumber >> (56 - 8*i))
+	}
+
+	var flags trace.TraceFlags
+	if options == "o=1" {
+		flags = trace.FlagsSampled
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+		Remote:     true,
+	}))
+}
+
+// Fields returns the header read by the propagator
+func (CloudTraceContext) Fields() []string {
+	return []string{CloudTraceContextHeader}
+}
+
+// Transport returns an http.RoundTripper injecting the trace context of the request's context
+// into the request headers before passing it to next, or http.DefaultTransport if next is nil
+func Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{next: next, propagator: Propagator()}
+}
+
+type transport struct {
+	next       http.RoundTripper
+	propagator propagation.TextMapPropagator
+}
+
+// RoundTrip sends a copy of the request with the trace context headers
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	t.propagator.Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+	return t.next.RoundTrip(req)
+}
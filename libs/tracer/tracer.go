@@ -68,6 +68,7 @@ func Init(ctx context.Context, projectID, applicationName string, gcp bool) (tr
 	}
 
 	otel.SetTracerProvider(traceProvider)
+	otel.SetTextMapPropagator(Propagator())
 	tracer = otel.GetTracerProvider().Tracer(applicationName)
 	return New(traceProvider, tracer), nil
 }